@@ -5,12 +5,13 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
-
-	"golang.org/x/sys/windows/registry"
+	"time"
 )
 
 type APIClient struct {
@@ -35,60 +36,237 @@ type APIUser struct {
 	Bindings map[string]APIBinding `json:"bindings"`
 }
 
+// Copy returns user with its own maps, so it can be changed without changing user
+func (user APIUser) Copy() APIUser {
+	copied := APIUser{Stations: map[string]APIStation{}, Bindings: map[string]APIBinding{}}
+
+	for id, station := range user.Stations {
+		copied.Stations[id] = station
+	}
+
+	for id, binding := range user.Bindings {
+		copied.Bindings[id] = binding
+	}
+
+	return copied
+}
+
+// APIPublicStation is a station anyone can bind to, as returned by a station search
+type APIPublicStation struct {
+	User string `json:"user"`
+	APIStation
+}
+
+type APIAccount struct {
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Handle string `json:"handle,omitempty"`
+}
+
+// APIPairing is a short-lived code another computer can use to log in to our account
+type APIPairing struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+type PartyMember struct {
+	ID     string `json:"id"`
+	Leader bool   `json:"leader"`
+}
+
 type PartyResponse struct {
-	Leader string `json:"leader"`
+	Leader  string        `json:"leader"`
+	Members []PartyMember `json:"members"`
+}
+
+const (
+	PartyQueuePolicyAll    = "all"
+	PartyQueuePolicyVote   = "vote"
+	PartyQueuePolicyLeader = "leader"
+)
+
+type PartyQueueEntry struct {
+	ID      string `json:"id"`
+	Station string `json:"station"`
+	Source  string `json:"source"`
+	User    string `json:"user"`
+	Votes   int    `json:"votes"`
+	Needed  int    `json:"needed"`
+	Queued  bool   `json:"queued"`
+}
+
+type PartyQueue struct {
+	Leader  string            `json:"leader"`
+	Policy  string            `json:"policy"`
+	Entries []PartyQueueEntry `json:"entries"`
 }
 
 func (c *APIClient) generateAuthHeader() string {
 	return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.ID+":"+c.Secret))
 }
 
-func (c *APIClient) Setup() {
-	k, err := registry.OpenKey(registry.CURRENT_USER, `SOFTWARE\FNRadio`, registry.QUERY_VALUE|registry.SET_VALUE)
+// do sends body as JSON to path and decodes the response into out, both of which may be nil
+func (c *APIClient) do(method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, APIRoot+path, reader)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	value, _, err := k.GetStringValue("APICredentials:" + APIRoot)
-	if err == nil {
-		split := strings.Split(value, ":")
+	// Registering is done before we have any credentials
+	if c.ID != "" {
+		request.Header.Add("Authorization", c.generateAuthHeader())
+	}
 
-		c.ID = split[0]
-		c.Secret = split[1]
-	} else {
-		request, err := http.NewRequest(http.MethodPost, APIRoot+"/users", nil)
-		if err != nil {
-			panic(err)
-		}
+	if body != nil {
+		request.Header.Add("Content-Type", "application/json")
+	}
 
-		response, err := http.DefaultClient.Do(request)
-		if err != nil {
-			panic(err)
-		}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
 
-		defer response.Body.Close()
+	defer response.Body.Close()
 
-		err = json.NewDecoder(response.Body).Decode(&c)
-		if err != nil {
-			panic(err)
-		}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		var errorResponse ErrorResponse
 
-		err = k.SetStringValue("APICredentials:"+APIRoot, c.ID+":"+c.Secret)
-		if err != nil {
-			panic(err)
+		err = json.NewDecoder(response.Body).Decode(&errorResponse)
+		if err != nil || errorResponse.Error == "" {
+			return errors.New("status code " + strconv.Itoa(response.StatusCode))
 		}
+
+		return errors.New(errorResponse.Error)
+	}
+
+	if out == nil || response.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+// accountsFile records which account we last saved credentials for on each API, it holds no secrets
+const accountsFile = "accounts.json"
+
+func apiCredentialName() string {
+	return "APICredentials:" + APIRoot
+}
+
+// savedAccount returns the account we last saved credentials for on the current API, if any
+func savedAccount() (string, error) {
+	accounts := map[string]string{}
+
+	err := loadConfig(accountsFile, &accounts)
+
+	return accounts[APIRoot], err
+}
+
+func rememberAccount(id string) error {
+	accounts := map[string]string{}
+
+	err := loadConfig(accountsFile, &accounts)
+	if err != nil || accounts[APIRoot] == id {
+		return err
+	}
+
+	accounts[APIRoot] = id
+
+	return saveConfig(accountsFile, accounts)
+}
+
+// Load reads the credentials saved for the current API
+func (c *APIClient) Load() error {
+	value, err := credentials.Load(apiCredentialName())
+	if err != nil {
+		return err
+	}
+
+	split := strings.SplitN(string(value), ":", 2)
+	if len(split) != 2 {
+		return errors.New("saved API credentials are invalid")
+	}
+
+	c.ID = split[0]
+	c.Secret = split[1]
+
+	return nil
+}
+
+func (c *APIClient) Save() error {
+	err := credentials.Save(apiCredentialName(), []byte(c.ID+":"+c.Secret))
+	if err != nil {
+		return err
+	}
+
+	return rememberAccount(c.ID)
+}
+
+// Register creates a new user and saves its credentials
+func (c *APIClient) Register() error {
+	var created APIClient
+
+	err := c.do(http.MethodPost, "/users", nil, &created)
+	if err != nil {
+		return err
+	}
+
+	*c = created
+
+	return c.Save()
+}
+
+// Setup loads the saved credentials, registering a new user only if there have never been any. Credentials that have
+// gone missing are an error, carrying on with a new account would leave the user without their stations.
+func (c *APIClient) Setup() {
+	err := c.Load()
+
+	switch {
+	case err == nil:
+		// Credentials saved before accounts were recorded
+		err = rememberAccount(c.ID)
+	case errors.Is(err, ErrCredentialNotFound):
+		err = c.registerIfNew()
 	}
 
-	err = k.Close()
 	if err != nil {
 		panic(err)
 	}
 }
 
+// registerIfNew registers a new user unless credentials have been saved for the current API before
+func (c *APIClient) registerIfNew() error {
+	saved, err := savedAccount()
+	if err != nil {
+		return err
+	}
+
+	if saved != "" {
+		return fmt.Errorf("the credentials for account %s can't be found, check secret_store in settings.json or run fnradio join with a code from account link on another computer", saved)
+	}
+
+	if registryHasSecret(apiCredentialName()) {
+		return errors.New("the credentials an older version left in the registry couldn't be moved, see the error above")
+	}
+
+	return c.Register()
+}
+
 func (c *APIClient) GetUser(id string) (APIUser, error) {
 	request, err := http.NewRequest(http.MethodGet, APIRoot+"/users/"+id, nil)
 	if err != nil {
@@ -125,6 +303,35 @@ func (c *APIClient) GetUser(id string) (APIUser, error) {
 	return user, nil
 }
 
+func (c *APIClient) GetCatalogue() (Catalogue, error) {
+	response, err := http.Get(APIRoot + "/catalogue")
+	if err != nil {
+		return Catalogue{}, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		var errorResponse ErrorResponse
+
+		err = json.NewDecoder(response.Body).Decode(&errorResponse)
+		if err != nil {
+			return Catalogue{}, err
+		}
+
+		return Catalogue{}, errors.New(errorResponse.Error)
+	}
+
+	var catalogue Catalogue
+
+	err = json.NewDecoder(response.Body).Decode(&catalogue)
+	if err != nil {
+		return Catalogue{}, err
+	}
+
+	return catalogue, nil
+}
+
 func (c *APIClient) CreateStation(station APIStation) error {
 	data, err := json.Marshal(station)
 	if err != nil {
@@ -282,28 +489,28 @@ func (c *APIClient) DeleteBinding(binding APIBinding) error {
 	return nil
 }
 
-func (c *APIClient) SetParty(party Party) (string, error) {
+func (c *APIClient) SetParty(party Party) (PartyResponse, error) {
 	data, err := json.Marshal(party)
 	if err != nil {
-		return "", err
+		return PartyResponse{}, err
 	}
 
 	request, err := http.NewRequest(http.MethodPost, APIRoot+"/users/@me/party", bytes.NewReader(data))
 	if err != nil {
-		return "", err
+		return PartyResponse{}, err
 	}
 
 	request.Header.Add("Authorization", c.generateAuthHeader())
 
 	response, err := http.DefaultClient.Do(request)
 	if err != nil {
-		return "", err
+		return PartyResponse{}, err
 	}
 
 	defer response.Body.Close()
 
 	if response.StatusCode == http.StatusNoContent {
-		return "", nil
+		return PartyResponse{}, nil
 	}
 
 	if response.StatusCode == http.StatusOK {
@@ -311,18 +518,107 @@ func (c *APIClient) SetParty(party Party) (string, error) {
 
 		err := json.NewDecoder(response.Body).Decode(&partyResponse)
 		if err != nil {
-			return "", err
+			return PartyResponse{}, err
 		}
 
-		return partyResponse.Leader, nil
+		return partyResponse, nil
 	}
 
 	var errorResponse ErrorResponse
 
 	err = json.NewDecoder(response.Body).Decode(&errorResponse)
 	if err != nil {
-		return "", err
+		return PartyResponse{}, err
 	}
 
-	return "", errors.New(errorResponse.Error)
+	return PartyResponse{}, errors.New(errorResponse.Error)
+}
+
+func (c *APIClient) PartyAddToQueue(station string, source string) (PartyQueueEntry, error) {
+	var entry PartyQueueEntry
+
+	err := c.do(http.MethodPut, "/users/@me/party/queue", map[string]string{"station": station, "source": source}, &entry)
+
+	return entry, err
+}
+
+func (c *APIClient) GetPartyQueue() (PartyQueue, error) {
+	var queue PartyQueue
+
+	err := c.do(http.MethodGet, "/users/@me/party/queue", nil, &queue)
+
+	return queue, err
+}
+
+func (c *APIClient) VotePartyQueue(id string) (PartyQueueEntry, error) {
+	var entry PartyQueueEntry
+
+	err := c.do(http.MethodPost, "/users/@me/party/queue/"+url.PathEscape(id)+"/vote", nil, &entry)
+
+	return entry, err
+}
+
+func (c *APIClient) SetPartyQueuePolicy(policy string) error {
+	return c.do(http.MethodPut, "/users/@me/party/policy", map[string]string{"queue": policy}, nil)
+}
+
+func (c *APIClient) SearchStations(query string) ([]APIPublicStation, error) {
+	var stations []APIPublicStation
+
+	err := c.do(http.MethodGet, "/stations?query="+url.QueryEscape(query), nil, &stations)
+
+	return stations, err
+}
+
+func (c *APIClient) GetAccount() (APIAccount, error) {
+	var account APIAccount
+
+	err := c.do(http.MethodGet, "/users/@me/account", nil, &account)
+
+	return account, err
+}
+
+// UpdateAccount changes the name and/or handle, empty fields are left as they are
+func (c *APIClient) UpdateAccount(account APIAccount) (APIAccount, error) {
+	var updated APIAccount
+
+	err := c.do(http.MethodPatch, "/users/@me/account", account, &updated)
+
+	return updated, err
+}
+
+// RotateSecret replaces our secret with a new one, the old one stops working straight away
+func (c *APIClient) RotateSecret() error {
+	var rotated APIClient
+
+	err := c.do(http.MethodPost, "/users/@me/secret", nil, &rotated)
+	if err != nil {
+		return err
+	}
+
+	c.Secret = rotated.Secret
+
+	return c.Save()
+}
+
+func (c *APIClient) DeleteAccount() error {
+	return c.do(http.MethodDelete, "/users/@me", nil, nil)
+}
+
+func (c *APIClient) CreatePairing() (APIPairing, error) {
+	var pairing APIPairing
+
+	err := c.do(http.MethodPost, "/users/@me/pairing", nil, &pairing)
+
+	return pairing, err
+}
+
+// JoinPairing swaps a pairing code for the credentials of the account that created it, which can only be done once.
+// The code is all the server needs, so this is sent without any credentials of our own
+func JoinPairing(code string) (APIClient, error) {
+	var anonymous, joined APIClient
+
+	err := anonymous.do(http.MethodPost, "/pairing/"+url.PathEscape(strings.ToUpper(code)), nil, &joined)
+
+	return joined, err
 }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePairingAPI hands out one pairing code for the account it was made with, which can be joined once
+type fakePairingAPI struct {
+	mu      sync.Mutex
+	account APIClient
+	code    string
+}
+
+func (f *fakePairingAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id, secret, authenticated := r.BasicAuth()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/users/@me/pairing":
+		if !authenticated || id != f.account.ID || secret != f.account.Secret {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "unauthorized"})
+
+			return
+		}
+
+		f.code = "ABC123"
+
+		_ = json.NewEncoder(w).Encode(APIPairing{Code: f.code, ExpiresAt: time.Now().Add(10 * time.Minute)})
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/pairing/"):
+		if authenticated {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "joining doesn't take credentials"})
+
+			return
+		}
+
+		if f.code == "" || strings.TrimPrefix(r.URL.Path, "/pairing/") != f.code {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "unknown or expired code"})
+
+			return
+		}
+
+		f.code = ""
+
+		_ = json.NewEncoder(w).Encode(f.account)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func fakePairingServer(t *testing.T, account APIClient) {
+	t.Helper()
+
+	server := httptest.NewServer(&fakePairingAPI{account: account})
+
+	oldRoot := APIRoot
+	APIRoot = server.URL
+
+	t.Cleanup(func() {
+		APIRoot = oldRoot
+
+		server.Close()
+	})
+}
+
+func TestPairing(t *testing.T) {
+	account := APIClient{ID: "user-a", Secret: "secret-a"}
+
+	fakePairingServer(t, account)
+
+	_, err := (&APIClient{ID: "user-a", Secret: "wrong"}).CreatePairing()
+	if err == nil || err.Error() != "unauthorized" {
+		t.Errorf("link with the wrong secret: got %v, want unauthorized", err)
+	}
+
+	pairing, err := account.CreatePairing()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Codes are shown in upper case but typing them in lower case should work too
+	joined, err := JoinPairing(strings.ToLower(pairing.Code))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if joined != account {
+		t.Errorf("joined %+v, want %+v", joined, account)
+	}
+
+	_, err = JoinPairing(pairing.Code)
+	if err == nil || err.Error() != "unknown or expired code" {
+		t.Errorf("joining twice: got %v, want the code to be used up", err)
+	}
+}
+
+func TestJoinSubcommand(t *testing.T) {
+	account := APIClient{ID: "user-a", Secret: "secret-a"}
+
+	fakePairingServer(t, account)
+	fakeStores(t)
+
+	pairing, err := account.CreatePairing()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := joinSubcommand([]string{pairing.Code}); code != 0 {
+		t.Fatalf("join exited with %d", code)
+	}
+
+	var saved APIClient
+
+	err = saved.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if saved != account {
+		t.Errorf("saved %+v, want %+v", saved, account)
+	}
+
+	if code := joinSubcommand([]string{pairing.Code}); code != 1 {
+		t.Errorf("joining twice exited with %d, want 1", code)
+	}
+}
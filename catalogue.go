@@ -0,0 +1,118 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+)
+
+const catalogueFile = "catalogue.json"
+
+type InGameStation struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	FirstSeason string   `json:"first_season,omitempty"`
+	LastSeason  string   `json:"last_season,omitempty"`
+	Icon        string   `json:"icon,omitempty"`
+}
+
+type Catalogue struct {
+	Version  int             `json:"version"`
+	Stations []InGameStation `json:"stations"`
+}
+
+//go:embed catalogue.json
+var bundledCatalogue []byte
+
+var catalogue = parseBundledCatalogue()
+
+var inGameStations = catalogue.Stations
+
+func parseBundledCatalogue() Catalogue {
+	var bundled Catalogue
+
+	err := json.Unmarshal(bundledCatalogue, &bundled)
+	if err != nil {
+		panic(err)
+	}
+
+	return bundled
+}
+
+func setCatalogue(c Catalogue) {
+	catalogue = c
+	inGameStations = append(append([]InGameStation{}, c.Stations...), discovery.NamedStations(c.Stations)...)
+}
+
+// loadCatalogue replaces the bundled catalogue with the local one if it is at least as new, and adds any discovered
+// stations the user has named
+func loadCatalogue() error {
+	err := loadDiscovery()
+	if err != nil {
+		return err
+	}
+
+	var local Catalogue
+
+	err = loadConfig(catalogueFile, &local)
+	if err != nil {
+		return err
+	}
+
+	if len(local.Stations) > 0 && local.Version >= catalogue.Version {
+		setCatalogue(local)
+	} else {
+		setCatalogue(catalogue)
+	}
+
+	return nil
+}
+
+// refreshCatalogue fetches the catalogue from the API and keeps it locally if it is newer than the current one
+func refreshCatalogue(api *APIClient) (bool, error) {
+	remote, err := api.GetCatalogue()
+	if err != nil {
+		return false, err
+	}
+
+	if remote.Version <= catalogue.Version || len(remote.Stations) == 0 {
+		return false, nil
+	}
+
+	err = saveConfig(catalogueFile, remote)
+	if err != nil {
+		return false, err
+	}
+
+	setCatalogue(remote)
+
+	return true, nil
+}
+
+func (station InGameStation) Matches(name string) bool {
+	if strings.EqualFold(station.Name, name) {
+		return true
+	}
+
+	for _, alias := range station.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (station InGameStation) Seasons() string {
+	switch {
+	case station.FirstSeason != "" && station.LastSeason != "":
+		return station.FirstSeason + " - " + station.LastSeason
+	case station.FirstSeason != "":
+		return "since " + station.FirstSeason
+	case station.LastSeason != "":
+		return "until " + station.LastSeason
+	}
+
+	return ""
+}
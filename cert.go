@@ -1,159 +1,212 @@
 package main
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"log"
 	"math/big"
-	"syscall"
+	"os"
+	"strings"
 	"time"
-	"unsafe"
-
-	"golang.org/x/sys/windows"
-	"golang.org/x/sys/windows/registry"
 )
 
-var (
-	crypt32                              = syscall.NewLazyDLL("crypt32.dll")
-	procCertAddEncodedCertificateToStore = crypt32.NewProc("CertAddEncodedCertificateToStore")
+const (
+	// caValidity is kept short so a leaked key is only useful for a while, the CA is replaced caRenewBefore it expires
+	caValidity    = 365 * 24 * time.Hour
+	caRenewBefore = 30 * 24 * time.Hour
 )
 
-func importCAToSystemRoot(cert *x509.Certificate) error {
-	data := cert.Raw
+// caPermittedDomains are the only domains the CA can issue certificates for
+var caPermittedDomains = []string{"akamaized.net", "epicgames.com"}
 
-	root, err := syscall.UTF16PtrFromString("root")
-	if err != nil {
-		return err
-	}
+func caNeedsRotation(cert *x509.Certificate) bool {
+	return time.Until(cert.NotAfter) < caRenewBefore || !cert.PermittedDNSDomainsCritical || len(cert.PermittedDNSDomains) == 0
+}
 
-	store, err := syscall.CertOpenStore(10, 0, 0, windows.CERT_SYSTEM_STORE_CURRENT_USER, uintptr(unsafe.Pointer(root)))
-	if err != nil {
-		return err
+// setupSSL loads the CA, making one only when there isn't one yet. Any other error stops us, a new CA would be trusted
+// alongside the one that couldn't be loaded.
+func setupSSL() *tls.Certificate {
+	certificate, err := getCertificate()
+	if err != nil && !errors.Is(err, ErrCredentialNotFound) {
+		panic(fmt.Errorf("the FNRadio certificate can't be loaded: %w", err))
 	}
 
-	defer syscall.CertCloseStore(store, 0) // nolint:errcheck
+	if certificate != nil {
+		x509Certificate, err := x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			panic(err)
+		}
 
-	_, _, err = procCertAddEncodedCertificateToStore.Call(uintptr(store), 1, uintptr(unsafe.Pointer(&data[0])), uintptr(uint(len(data))), 4, 0)
-	if !errors.Is(err, windows.ERROR_SUCCESS) {
-		return err
+		if !caNeedsRotation(x509Certificate) {
+			trusted, err := trustStore.Contains(x509Certificate)
+			if err == nil && !trusted {
+				err = trustStore.Add(x509Certificate)
+			}
+
+			if err != nil {
+				panic(err)
+			}
+
+			return certificate
+		}
+
+		fmt.Println("Replacing the FNRadio certificate, Windows may ask you to confirm removing the old one")
+
+		certificate, err = rotateCA(x509Certificate)
+		if err != nil {
+			panic(err)
+		}
+
+		return certificate
 	}
 
-	return nil
-}
+	// A certificate whose key has gone missing can't be used, it's replaced rather than left trusted
+	old, _ := storedCA()
 
-func systemHasCertificate(certToFind *x509.Certificate) bool {
-	root, err := syscall.UTF16PtrFromString("root")
+	certificate, err = rotateCA(old)
 	if err != nil {
-		return false
+		panic(err)
 	}
 
-	store, err := syscall.CertOpenStore(10, 0, 0, windows.CERT_SYSTEM_STORE_CURRENT_USER, uintptr(unsafe.Pointer(root)))
+	return certificate
+}
+
+// storedCA returns the saved CA certificate on its own, which is all that's needed to stop trusting it
+func storedCA() (*x509.Certificate, error) {
+	der, err := credentials.Load(sslCertificateCredential)
 	if err != nil {
-		return false
+		return nil, err
 	}
 
-	defer syscall.CertCloseStore(store, 0) // nolint:errcheck
+	return x509.ParseCertificate(der)
+}
 
-	var cert *syscall.CertContext
+// rotateCA replaces the CA with a new one, the old one is no longer trusted once the new one is
+func rotateCA(old *x509.Certificate) (*tls.Certificate, error) {
+	certificate, err := createCACertificate()
+	if err != nil {
+		return nil, err
+	}
 
-	for {
-		cert, err = syscall.CertEnumCertificatesInStore(store, cert)
+	if old != nil {
+		err = trustStore.Remove(old)
 		if err != nil {
-			break
+			log.Println("WARN: Failed to remove the old FNRadio certificate: " + err.Error())
 		}
+	}
 
-		buf := (*[1 << 20]byte)(unsafe.Pointer(cert.EncodedCert))[:]
-		buf2 := make([]byte, cert.Length)
+	return certificate, nil
+}
 
-		copy(buf2, buf)
+// uninstallCA stops the CA being trusted and forgets it, a new one is made the next time the proxy starts
+func uninstallCA() error {
+	certificate, err := getCertificate()
+	if err != nil {
+		return errors.New("there is no FNRadio certificate")
+	}
 
-		c, err := x509.ParseCertificate(buf2)
-		if err != nil {
-			panic(err)
-		}
+	x509Certificate, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return err
+	}
 
-		if c.Equal(certToFind) {
-			return true
-		}
+	err = trustStore.Remove(x509Certificate)
+	if err != nil {
+		return err
+	}
+
+	err = credentials.Delete(sslPrivateKeyCredential)
+	if err != nil {
+		return err
 	}
 
-	return false
+	return credentials.Delete(sslCertificateCredential)
 }
 
-func setupSSL() *tls.Certificate {
-	var err error
+func caFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
 
-	certificate, _ := getCertificate()
+	hex := make([]string, len(sum))
 
-	if certificate != nil {
-		x509Certificate, err := x509.ParseCertificate(certificate.Certificate[0])
-		if err != nil {
-			panic(err)
-		}
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02X", b)
+	}
 
-		if !systemHasCertificate(x509Certificate) {
-			err = importCAToSystemRoot(x509Certificate)
-			if err != nil {
-				panic(err)
-			}
-		}
+	return strings.Join(hex, ":")
+}
 
-		return certificate
-	}
+func exportCA(cert *x509.Certificate, file string) error {
+	return os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0644)
+}
 
-	certificate, err = createCACertificate()
-	if err != nil {
-		panic(err)
+// parsePrivateKey reads the stored CA key, which is PKCS#8 but was PKCS#1 before the CA used ECDSA
+func parsePrivateKey(der []byte) (crypto.PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err == nil {
+		return key, nil
 	}
 
-	return certificate
+	return x509.ParsePKCS1PrivateKey(der)
 }
 
 func getCertificate() (*tls.Certificate, error) {
-	k, err := registry.OpenKey(registry.CURRENT_USER, `SOFTWARE\FNRadio`, registry.QUERY_VALUE)
-	if err != nil {
-		return nil, err
-	}
-
-	regCert, _, err := k.GetBinaryValue("SSLCertificate")
+	storedCert, err := credentials.Load(sslCertificateCredential)
 	if err != nil {
 		return nil, err
 	}
 
-	regKey, _, err := k.GetBinaryValue("SSLPrivateKey")
+	storedKey, err := credentials.Load(sslPrivateKeyCredential)
 	if err != nil {
 		return nil, err
 	}
 
-	key, err := x509.ParsePKCS1PrivateKey(regKey)
+	key, err := parsePrivateKey(storedKey)
 	if err != nil {
 		return nil, err
 	}
 
 	return &tls.Certificate{
-		Certificate: [][]byte{regCert},
+		Certificate: [][]byte{storedCert},
 		PrivateKey:  key,
 	}, nil
 }
 
 func createCACertificate() (*tls.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
 	ca := &x509.Certificate{
-		SerialNumber: big.NewInt(0),
+		SerialNumber: serial,
 		Subject: pkix.Name{
 			CommonName: "FNRadio",
 		},
-		NotBefore:             time.Now(),
-		NotAfter:              time.Now().AddDate(10, 0, 0),
-		IsCA:                  true,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
-		BasicConstraintsValid: true,
+		NotBefore:                   time.Now().Add(-time.Hour),
+		NotAfter:                    time.Now().Add(caValidity),
+		IsCA:                        true,
+		KeyUsage:                    x509.KeyUsageCertSign,
+		BasicConstraintsValid:       true,
+		MaxPathLenZero:              true,
+		PermittedDNSDomainsCritical: true,
+		PermittedDNSDomains:         caPermittedDomains,
 	}
 
-	caPrivKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	caPrivKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(caPrivKey)
 	if err != nil {
 		return nil, err
 	}
@@ -168,21 +221,21 @@ func createCACertificate() (*tls.Certificate, error) {
 		return nil, err
 	}
 
-	err = importCAToSystemRoot(parsed)
+	// The CA is only trusted once both halves are saved, so a failure never leaves a trusted CA we can't use or remove
+	err = credentials.Save(sslCertificateCredential, caBytes)
 	if err != nil {
 		return nil, err
 	}
 
-	k, _, err := registry.CreateKey(registry.CURRENT_USER, `SOFTWARE\FNRadio`, registry.SET_VALUE)
+	err = credentials.Save(sslPrivateKeyCredential, der)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	_ = k.SetBinaryValue("SSLCertificate", caBytes)
-
-	_ = k.SetBinaryValue("SSLPrivateKey", x509.MarshalPKCS1PrivateKey(caPrivKey))
-
-	_ = k.Close()
+	err = trustStore.Add(parsed)
+	if err != nil {
+		return nil, err
+	}
 
 	return &tls.Certificate{
 		Certificate: [][]byte{caBytes},
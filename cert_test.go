@@ -0,0 +1,280 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/elazarl/goproxy"
+)
+
+// fakeTrustStore is a TrustStore kept in memory, keyed by the raw certificate
+type fakeTrustStore map[string]bool
+
+func (s fakeTrustStore) Contains(cert *x509.Certificate) (bool, error) {
+	return s[string(cert.Raw)], nil
+}
+
+func (s fakeTrustStore) Add(cert *x509.Certificate) error {
+	s[string(cert.Raw)] = true
+
+	return nil
+}
+
+func (s fakeTrustStore) Remove(cert *x509.Certificate) error {
+	delete(s, string(cert.Raw))
+
+	return nil
+}
+
+// memoryCredentials is a CredentialStore kept in memory
+type memoryCredentials map[string][]byte
+
+func (m memoryCredentials) Load(name string) ([]byte, error) {
+	value, ok := m[name]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+
+	return value, nil
+}
+
+func (m memoryCredentials) Save(name string, value []byte) error {
+	m[name] = value
+
+	return nil
+}
+
+func (m memoryCredentials) Delete(name string) error {
+	delete(m, name)
+
+	return nil
+}
+
+// fakeStores swaps the trust and credential stores for in-memory ones for the rest of the test
+func fakeStores(t *testing.T) (fakeTrustStore, memoryCredentials) {
+	t.Helper()
+
+	oldTrust, oldCredentials := trustStore, credentials
+	trust, creds := fakeTrustStore{}, memoryCredentials{}
+
+	trustStore, credentials = trust, creds
+
+	t.Cleanup(func() {
+		trustStore, credentials = oldTrust, oldCredentials
+	})
+
+	return trust, creds
+}
+
+func currentCA(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	certificate, err := getCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func TestCreateCACertificate(t *testing.T) {
+	trust, _ := fakeStores(t)
+
+	_, err := createCACertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := currentCA(t)
+
+	if !trust[string(cert.Raw)] || len(trust) != 1 {
+		t.Errorf("trust store has %d certificates, want only the new CA", len(trust))
+	}
+
+	if caNeedsRotation(cert) {
+		t.Error("a new CA needs rotating")
+	}
+}
+
+// signLeaf has the proxy sign a certificate for host like it does when a connection is intercepted
+func signLeaf(t *testing.T, ca *tls.Certificate, host string) *tls.Certificate {
+	t.Helper()
+
+	config, err := goproxy.TLSConfigFromCA(ca)(host, &goproxy.ProxyCtx{Proxy: goproxy.NewProxyHttpServer()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &config.Certificates[0]
+}
+
+func TestCAPermittedDomains(t *testing.T) {
+	fakeStores(t)
+
+	ca, err := createCACertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(currentCA(t))
+
+	tests := []struct {
+		host  string
+		valid bool
+	}{
+		{"fortnite-vod.akamaized.net:443", true},
+		{"account-public-service-prod.ol.epicgames.com:443", true},
+		{"example.com:443", false},
+		{"akamaized.net.example.com:443", false},
+		{"notepicgames.com:443", false},
+	}
+
+	for _, test := range tests {
+		leaf, err := x509.ParseCertificate(signLeaf(t, ca, test.host).Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The CA is trusted by the system, so the name constraints are all that keep it from vouching for other sites
+		_, err = leaf.Verify(x509.VerifyOptions{Roots: roots})
+
+		if test.valid && err != nil {
+			t.Errorf("%s: %v", test.host, err)
+		}
+
+		if !test.valid && err == nil {
+			t.Errorf("%s: verified outside the permitted domains", test.host)
+		}
+	}
+}
+
+type failingTrustStore struct{ fakeTrustStore }
+
+func (failingTrustStore) Add(*x509.Certificate) error {
+	return errors.New("denied")
+}
+
+func TestCreateCACertificateTrustFails(t *testing.T) {
+	fakeStores(t)
+
+	trustStore = failingTrustStore{fakeTrustStore{}}
+
+	_, err := createCACertificate()
+	if err == nil {
+		t.Fatal("expected the trust store error")
+	}
+}
+
+func TestRotateCA(t *testing.T) {
+	trust, _ := fakeStores(t)
+
+	// A certificate we didn't make has to survive the rotation
+	other, err := createCACertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherCert, err := x509.ParseCertificate(other.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = createCACertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := currentCA(t)
+
+	_, err = rotateCA(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := currentCA(t)
+
+	switch {
+	case current.Equal(old):
+		t.Error("the CA wasn't replaced")
+	case trust[string(old.Raw)]:
+		t.Error("the old CA is still trusted")
+	case !trust[string(current.Raw)]:
+		t.Error("the new CA isn't trusted")
+	case !trust[string(otherCert.Raw)]:
+		t.Error("another certificate was removed")
+	}
+}
+
+func TestCertCommand(t *testing.T) {
+	trust, creds := fakeStores(t)
+
+	_, err := certCommand(nil)
+	if !errors.Is(err, errCertUsage) {
+		t.Errorf("no arguments: got %v, want the usage", err)
+	}
+
+	_, err = certCommand([]string{"status"})
+	if err == nil {
+		t.Error("status without a CA: expected an error")
+	}
+
+	_, err = createCACertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := currentCA(t)
+
+	_, err = certCommand([]string{"status"})
+	if err != nil {
+		t.Errorf("status: %v", err)
+	}
+
+	_, err = certCommand([]string{"export"})
+	if !errors.Is(err, errCertUsage) {
+		t.Errorf("export without a file: got %v, want the usage", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "fnradio.pem")
+
+	_, err = certCommand([]string{"export", file})
+	if err != nil {
+		t.Errorf("export: %v", err)
+	}
+
+	certificate, err := certCommand([]string{"rotate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if certificate == nil || currentCA(t).Equal(old) {
+		t.Error("rotate didn't return a new CA")
+	}
+
+	if trust[string(old.Raw)] || len(trust) != 1 {
+		t.Errorf("trust store has %d certificates after rotate, want only the new CA", len(trust))
+	}
+
+	_, err = certCommand([]string{"uninstall"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(trust) != 0 || len(creds) != 0 {
+		t.Errorf("uninstall left %d trusted certificates and %d credentials", len(trust), len(creds))
+	}
+
+	_, err = certCommand([]string{"bogus"})
+	if err == nil {
+		t.Error("unknown command after uninstall: expected an error")
+	}
+}
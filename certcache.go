@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"sync"
+	"time"
+
+	"github.com/elazarl/goproxy"
+)
+
+// CertCache keeps the CA and the certificates the proxy signs with it for each host, so they are only signed again
+// once they expire or the CA changes. It's used as the proxy's goproxy.CertStorage.
+type CertCache struct {
+	mu     sync.Mutex
+	ca     *tls.Certificate
+	caCert *x509.Certificate
+	certs  map[string]*tls.Certificate
+}
+
+func NewCertCache() *CertCache {
+	return &CertCache{certs: map[string]*tls.Certificate{}}
+}
+
+// CA returns the certificate new connections are signed with
+func (cache *CertCache) CA() *tls.Certificate {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	return cache.ca
+}
+
+// SetCA replaces the CA and forgets every certificate the old one signed
+func (cache *CertCache) SetCA(ca *tls.Certificate) error {
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.ca = ca
+	cache.caCert = caCert
+	cache.certs = map[string]*tls.Certificate{}
+
+	return nil
+}
+
+// TLSConfig signs for host with the current CA, it's the TLSConfig of the proxy's MITM connections
+func (cache *CertCache) TLSConfig(host string, ctx *goproxy.ProxyCtx) (*tls.Config, error) {
+	return goproxy.TLSConfigFromCA(cache.CA())(host, ctx)
+}
+
+func (cache *CertCache) Fetch(hostname string, gen func() (*tls.Certificate, error)) (*tls.Certificate, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var key string
+
+	if cache.caCert != nil {
+		key = caFingerprint(cache.caCert) + " " + hostname
+	}
+
+	if cert, ok := cache.certs[key]; ok && time.Now().Before(cert.Leaf.NotAfter) {
+		return cert, nil
+	}
+
+	cert, err := gen()
+	if err != nil {
+		return nil, err
+	}
+
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// gen signs with the CA the connection started with, which might have been replaced since then
+	if cache.caCert == nil || cert.Leaf.CheckSignatureFrom(cache.caCert) != nil {
+		return cert, nil
+	}
+
+	cache.certs[key] = cert
+
+	return cert, nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCA makes a CA the way setupSSL does, with the stores swapped for in-memory ones
+func testCA(t *testing.T) *tls.Certificate {
+	t.Helper()
+
+	fakeStores(t)
+
+	ca, err := createCACertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ca
+}
+
+// countingGen signs certificates with ca that are valid until notAfter, counting how many were signed
+func countingGen(t *testing.T, ca *tls.Certificate, notAfter time.Time, count *int) func() (*tls.Certificate, error) {
+	t.Helper()
+
+	parent, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func() (*tls.Certificate, error) {
+		*count++
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(*count)),
+			DNSNames:     []string{"fortnite-vod.akamaized.net"},
+			NotBefore:    notAfter.Add(-time.Hour),
+			NotAfter:     notAfter,
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, ca.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+	}
+}
+
+func TestCertCacheFetch(t *testing.T) {
+	ca := testCA(t)
+	cache := NewCertCache()
+
+	if err := cache.SetCA(ca); err != nil {
+		t.Fatal(err)
+	}
+
+	generated := 0
+	gen := countingGen(t, ca, time.Now().Add(time.Hour), &generated)
+
+	first, err := cache.Fetch("fortnite-vod.akamaized.net", gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := cache.Fetch("fortnite-vod.akamaized.net", gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if generated != 1 || second != first {
+		t.Errorf("signed %d certificates for one host, want the cached one", generated)
+	}
+
+	if _, err := cache.Fetch("account-public-service-prod.ol.epicgames.com", gen); err != nil || generated != 2 {
+		t.Errorf("signed %d certificates for two hosts: %v", generated, err)
+	}
+
+	newCA, err := createCACertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.SetCA(newCA); err != nil {
+		t.Fatal(err)
+	}
+
+	generated = 0
+	gen = countingGen(t, newCA, time.Now().Add(time.Hour), &generated)
+
+	replaced, err := cache.Fetch("fortnite-vod.akamaized.net", gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if generated != 1 || replaced == first {
+		t.Errorf("signed %d certificates, want a new one after the CA changed", generated)
+	}
+}
+
+func TestCertCacheExpiry(t *testing.T) {
+	ca := testCA(t)
+	cache := NewCertCache()
+
+	if err := cache.SetCA(ca); err != nil {
+		t.Fatal(err)
+	}
+
+	generated := 0
+	gen := countingGen(t, ca, time.Now().Add(-time.Minute), &generated)
+
+	for i := 1; i <= 2; i++ {
+		if _, err := cache.Fetch("fortnite-vod.akamaized.net", gen); err != nil {
+			t.Fatal(err)
+		}
+
+		if generated != i {
+			t.Errorf("signed %d certificates, want an expired one to be signed again", generated)
+		}
+	}
+}
+
+func TestCertCacheOldCA(t *testing.T) {
+	old := testCA(t)
+	cache := NewCertCache()
+
+	current, err := createCACertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.SetCA(current); err != nil {
+		t.Fatal(err)
+	}
+
+	// A connection that started before the CA was rotated still signs with the old one
+	generated := 0
+	gen := countingGen(t, old, time.Now().Add(time.Hour), &generated)
+
+	for i := 1; i <= 2; i++ {
+		if _, err := cache.Fetch("fortnite-vod.akamaized.net", gen); err != nil {
+			t.Fatal(err)
+		}
+
+		if generated != i {
+			t.Errorf("signed %d certificates, a certificate from the old CA was cached", generated)
+		}
+	}
+}
+
+// TestCertCacheRotateDuringFetch replaces the CA while connections are being signed, run with -race
+func TestCertCacheRotateDuringFetch(t *testing.T) {
+	cas := []*tls.Certificate{testCA(t)}
+
+	for i := 0; i < 3; i++ {
+		ca, err := createCACertificate()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cas = append(cas, ca)
+	}
+
+	cache := NewCertCache()
+
+	if err := cache.SetCA(cas[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 20; i++ {
+			if err := cache.SetCA(cas[i%len(cas)]); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		generated := 0
+
+		if _, err := cache.Fetch("fortnite-vod.akamaized.net", countingGen(t, cache.CA(), time.Now().Add(time.Hour), &generated)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wg.Wait()
+
+	parent, err := x509.ParseCertificate(cache.CA().Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generated := 0
+
+	// Whatever is left in the cache was signed by the CA now in use
+	leaf, err := cache.Fetch("fortnite-vod.akamaized.net", countingGen(t, cache.CA(), time.Now().Add(time.Hour), &generated))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := leaf.Leaf.CheckSignatureFrom(parent); err != nil {
+		t.Errorf("cached certificate wasn't signed by the current CA: %v", err)
+	}
+}
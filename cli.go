@@ -2,17 +2,20 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/c-bata/go-prompt"
+	"github.com/skip2/go-qrcode"
 )
 
 type CLI struct {
-}
-
-type InGameStation struct {
-	ID   string
-	Name string
+	mu     sync.Mutex
+	status string
 }
 
 const (
@@ -21,25 +24,35 @@ const (
 )
 
 const (
-	CreateCmd  = "create"
-	PlayCmd    = "play"
-	DeleteCmd  = "delete"
-	BindCmd    = "bind"
-	BindAllCmd = "bindall"
-	UnbindCmd  = "unbind"
-	BindsCmd   = "binds"
+	CreateCmd     = "create"
+	PlayCmd       = "play"
+	DeleteCmd     = "delete"
+	BindCmd       = "bind"
+	BindAllCmd    = "bindall"
+	UnbindCmd     = "unbind"
+	BindsCmd      = "binds"
+	RenameCmd     = "rename"
+	CloneCmd      = "clone"
+	UpdateCmd     = "update"
+	ExportCmd     = "export"
+	ImportCmd     = "import"
+	ApplyCmd      = "apply"
+	CatalogueCmd  = "catalogue"
+	DiscoveredCmd = "discovered"
+	PartyCmd      = "party"
+	PartyPlayCmd  = "partyplay"
+	PartyQueueCmd = "partyqueue"
+	MetricsCmd    = "metrics"
+	PhaseCmd      = "phase"
+	ScheduleCmd   = "schedule"
+	WhyPlaysCmd   = "whyplays"
+	BrowseCmd     = "browse"
+	FollowCmd     = "follow"
+	UnfollowCmd   = "unfollow"
+	AccountCmd    = "account"
+	CertCmd       = "cert"
 )
 
-var inGameStations = []InGameStation{
-	{ID: "saeOLZXrNKpBEPGRBQ", Name: "Icon Radio"},
-	{ID: "hgsuJcchvKuaEzzijr", Name: "Rock & Royale"},
-	{ID: "VlYSRdFWOKyyhNNNgr", Name: "Radio Underground"},
-	{ID: "DGeVaWdcXtfpbAaP", Name: "Party Royale"},
-	{ID: "GEviYjIhzVVzJufW", Name: "Radio Yonder"},
-	{ID: "BXrDueZkosvNvxtx", Name: "Beat Box"},
-	{ID: "PcQCHxHkBsmjSneR", Name: "Power Play"},
-}
-
 func getInGameStationByName(name string) (InGameStation, bool) {
 	for _, station := range inGameStations {
 		if station.Name == name {
@@ -47,9 +60,25 @@ func getInGameStationByName(name string) (InGameStation, bool) {
 		}
 	}
 
+	for _, station := range inGameStations {
+		if station.Matches(name) {
+			return station, true
+		}
+	}
+
 	return InGameStation{}, false
 }
 
+func getInGameStationName(id string) string {
+	for _, station := range inGameStations {
+		if station.ID == id {
+			return station.Name
+		}
+	}
+
+	return id
+}
+
 func (cli *CLI) completer(d prompt.Document) []prompt.Suggest {
 	split := strings.Split(d.CurrentLine(), " ")
 
@@ -57,6 +86,15 @@ func (cli *CLI) completer(d prompt.Document) []prompt.Suggest {
 
 	index := 0
 
+	if len(split) == 2 && (split[0] == BindCmd || split[0] == UnbindCmd) {
+		s = append(s, prompt.Suggest{Text: "--local", Description: "Only keeps the binding on this computer"})
+	}
+
+	// --local doesn't change what comes after it
+	if len(split) > 2 && (split[0] == BindCmd || split[0] == UnbindCmd) && split[1] == "--local" {
+		split = append([]string{split[0]}, split[2:]...)
+	}
+
 	if len(split) == 1 {
 		s = append(s, prompt.Suggest{Text: CreateCmd, Description: "Create a station"})
 		s = append(s, prompt.Suggest{Text: PlayCmd, Description: "Plays a song on a station"})
@@ -65,6 +103,26 @@ func (cli *CLI) completer(d prompt.Document) []prompt.Suggest {
 		s = append(s, prompt.Suggest{Text: BindAllCmd, Description: "Bind a station to all in-game stations"})
 		s = append(s, prompt.Suggest{Text: BindsCmd, Description: "Lists all bound stations"})
 		s = append(s, prompt.Suggest{Text: UnbindCmd, Description: "Unbind an in-game station"})
+		s = append(s, prompt.Suggest{Text: RenameCmd, Description: "Renames a station"})
+		s = append(s, prompt.Suggest{Text: CloneCmd, Description: "Copies a station to a new ID"})
+		s = append(s, prompt.Suggest{Text: UpdateCmd, Description: "Changes the type or source of a station"})
+		s = append(s, prompt.Suggest{Text: ExportCmd, Description: "Saves your stations and bindings to a file"})
+		s = append(s, prompt.Suggest{Text: ImportCmd, Description: "Loads stations and bindings from a file"})
+		s = append(s, prompt.Suggest{Text: ApplyCmd, Description: "Makes your stations and bindings match a file exactly"})
+		s = append(s, prompt.Suggest{Text: CatalogueCmd, Description: "Lists the known in-game stations"})
+		s = append(s, prompt.Suggest{Text: DiscoveredCmd, Description: "Lists in-game stations seen by the proxy"})
+		s = append(s, prompt.Suggest{Text: PartyCmd, Description: "Shows whose stations your party is hearing"})
+		s = append(s, prompt.Suggest{Text: PartyPlayCmd, Description: "Queues a song on the party leader's stream station"})
+		s = append(s, prompt.Suggest{Text: PartyQueueCmd, Description: "Shows the party queue, votes on songs or sets who can queue"})
+		s = append(s, prompt.Suggest{Text: MetricsCmd, Description: "Shows counts of game events seen this session"})
+		s = append(s, prompt.Suggest{Text: PhaseCmd, Description: "Plays a different station during a match phase"})
+		s = append(s, prompt.Suggest{Text: ScheduleCmd, Description: "Plays a different station at certain times"})
+		s = append(s, prompt.Suggest{Text: WhyPlaysCmd, Description: "Explains what an in-game station plays"})
+		s = append(s, prompt.Suggest{Text: BrowseCmd, Description: "Searches public stations, or lists followed users' stations"})
+		s = append(s, prompt.Suggest{Text: FollowCmd, Description: "Follows a user so their stations show up in browse"})
+		s = append(s, prompt.Suggest{Text: UnfollowCmd, Description: "Stops following a user"})
+		s = append(s, prompt.Suggest{Text: AccountCmd, Description: "Shows or changes your FNRadio account"})
+		s = append(s, prompt.Suggest{Text: CertCmd, Description: "Shows, replaces, exports or removes the FNRadio certificate"})
 	}
 
 	if len(split) == 2 && split[0] == CreateCmd {
@@ -73,21 +131,140 @@ func (cli *CLI) completer(d prompt.Document) []prompt.Suggest {
 		s = append(s, prompt.Suggest{Text: split[1], Description: "The station ID"})
 	}
 
-	if len(split) == 3 && split[0] == CreateCmd {
+	if len(split) == 3 && (split[0] == CreateCmd || split[0] == UpdateCmd) {
 		index = 2
 
 		s = append(s, prompt.Suggest{Text: StationTypeStatic})
 		s = append(s, prompt.Suggest{Text: StationTypeStream})
 	}
 
-	if len(split) == 2 && (split[0] == PlayCmd || split[0] == DeleteCmd || split[0] == BindCmd || split[0] == BindAllCmd) {
+	if len(split) == 2 && (split[0] == PlayCmd || split[0] == DeleteCmd || split[0] == BindCmd || split[0] == BindAllCmd ||
+		split[0] == RenameCmd || split[0] == CloneCmd || split[0] == UpdateCmd) {
+		index = 1
+
+		for _, station := range client.self().Stations {
+			s = append(s, prompt.Suggest{Text: station.ID})
+		}
+	}
+
+	if len(split) == 2 && split[0] == CatalogueCmd {
+		index = 1
+
+		s = append(s, prompt.Suggest{Text: "refresh", Description: "Fetches the latest in-game stations"})
+	}
+
+	if len(split) == 2 && split[0] == DiscoveredCmd {
+		index = 1
+
+		s = append(s, prompt.Suggest{Text: "name", Description: "Names a discovered station so it can be bound"})
+	}
+
+	if len(split) == 3 && split[0] == DiscoveredCmd && split[1] == "name" {
+		index = 2
+
+		for _, station := range discovery.List() {
+			if !containsInGameStation(inGameStations, station.ID) {
+				s = append(s, prompt.Suggest{Text: station.ID})
+			}
+		}
+	}
+
+	if len(split) == 2 && split[0] == PartyCmd {
+		index = 1
+
+		s = append(s, prompt.Suggest{Text: PartyModeFollow, Description: "Use the party leader's stations in a match"})
+		s = append(s, prompt.Suggest{Text: PartyModeSelf, Description: "Always use your own stations"})
+	}
+
+	if len(split) == 2 && split[0] == PartyPlayCmd {
+		index = 1
+
+		for _, station := range client.boundUser().Stations {
+			if station.Type == StationTypeStream {
+				s = append(s, prompt.Suggest{Text: station.ID})
+			}
+		}
+	}
+
+	if len(split) == 2 && split[0] == PartyQueueCmd {
+		index = 1
+
+		s = append(s, prompt.Suggest{Text: "vote", Description: "Votes for a song to be queued"})
+		s = append(s, prompt.Suggest{Text: "policy", Description: "Sets who can queue songs on your stations"})
+	}
+
+	if len(split) == 3 && split[0] == PartyQueueCmd && split[1] == "policy" {
+		index = 2
+
+		s = append(s, prompt.Suggest{Text: PartyQueuePolicyAll, Description: "Anyone in the party can queue songs"})
+		s = append(s, prompt.Suggest{Text: PartyQueuePolicyVote, Description: "Songs are queued once enough of the party votes for them"})
+		s = append(s, prompt.Suggest{Text: PartyQueuePolicyLeader, Description: "Only you can queue songs"})
+	}
+
+	if len(split) >= 2 && split[0] == PhaseCmd {
+		s, index = cli.phaseCompleter(split)
+	}
+
+	if len(split) >= 2 && split[0] == WhyPlaysCmd {
+		index = 1
+
+		for _, station := range inGameStations {
+			s = append(s, prompt.Suggest{Text: station.Name})
+		}
+	}
+
+	if len(split) == 2 && split[0] == ScheduleCmd {
 		index = 1
 
-		for _, station := range client.Users[client.APIClient.ID].Stations {
+		s = append(s, prompt.Suggest{Text: "add", Description: "Adds a scheduled binding"})
+		s = append(s, prompt.Suggest{Text: "remove", Description: "Removes a scheduled binding by number"})
+	}
+
+	if len(split) == 3 && split[0] == ScheduleCmd && split[1] == "add" {
+		index = 2
+
+		for _, station := range client.self().Stations {
 			s = append(s, prompt.Suggest{Text: station.ID})
 		}
 	}
 
+	if len(split) >= 5 && split[0] == ScheduleCmd && split[1] == "add" {
+		index = 4
+
+		for _, station := range inGameStations {
+			s = append(s, prompt.Suggest{Text: station.Name})
+		}
+	}
+
+	if len(split) == 2 && split[0] == AccountCmd {
+		index = 1
+
+		s = append(s, prompt.Suggest{Text: "name", Description: "Sets your display name"})
+		s = append(s, prompt.Suggest{Text: "handle", Description: "Sets the handle others can find you by"})
+		s = append(s, prompt.Suggest{Text: "rotate", Description: "Replaces your secret with a new one"})
+		s = append(s, prompt.Suggest{Text: "login", Description: "Uses an existing account's ID and secret"})
+		s = append(s, prompt.Suggest{Text: "reset", Description: "Deletes your account and starts a new one"})
+		s = append(s, prompt.Suggest{Text: "link", Description: "Shows a code to use this account on another computer"})
+		s = append(s, prompt.Suggest{Text: "join", Description: "Uses the account from another computer's link code"})
+	}
+
+	if len(split) == 2 && split[0] == CertCmd {
+		index = 1
+
+		s = append(s, prompt.Suggest{Text: "status", Description: "Shows the certificate's fingerprint and expiry and whether it's trusted"})
+		s = append(s, prompt.Suggest{Text: "rotate", Description: "Replaces the certificate with a new one"})
+		s = append(s, prompt.Suggest{Text: "export", Description: "Saves the certificate as PEM"})
+		s = append(s, prompt.Suggest{Text: "uninstall", Description: "Removes the certificate and exits"})
+	}
+
+	if len(split) == 2 && split[0] == UnfollowCmd {
+		index = 1
+
+		for _, user := range following.List() {
+			s = append(s, prompt.Suggest{Text: user})
+		}
+	}
+
 	if len(split) >= 3 && split[0] == BindCmd {
 		index = 2
 
@@ -100,7 +277,10 @@ func (cli *CLI) completer(d prompt.Document) []prompt.Suggest {
 		index = 1
 
 		for _, station := range inGameStations {
-			if _, ok := client.Users[client.APIClient.ID].Bindings[station.ID]; ok {
+			_, bound := client.self().Bindings[station.ID]
+			_, boundLocally := localBindings.Get(station.ID)
+
+			if bound || boundLocally {
 				s = append(s, prompt.Suggest{Text: station.Name})
 			}
 		}
@@ -109,17 +289,7 @@ func (cli *CLI) completer(d prompt.Document) []prompt.Suggest {
 	return prompt.FilterHasPrefix(s, strings.Join(split[index:], " "), true)
 }
 
-func (cli *CLI) createCmd(args []string) {
-	if len(args) < 2 {
-		fmt.Println("Usage: create <id> <type>")
-		return
-	}
-
-	if _, ok := client.Users[client.APIClient.ID].Stations[args[0]]; ok {
-		fmt.Println("Station already exists")
-		return
-	}
-
+func stationFromArgs(cmd string, args []string) (APIStation, bool) {
 	station := APIStation{
 		ID:   args[0],
 		Type: args[1],
@@ -128,8 +298,8 @@ func (cli *CLI) createCmd(args []string) {
 	switch args[1] {
 	case StationTypeStatic:
 		if len(args) < 3 {
-			fmt.Println("Usage: create <id> static <folder>")
-			return
+			fmt.Printf("Usage: %s <id> static <folder>\n", cmd)
+			return APIStation{}, false
 		}
 
 		station.Source = strings.Join(args[2:], " ")
@@ -137,6 +307,79 @@ func (cli *CLI) createCmd(args []string) {
 		break
 	default:
 		fmt.Println("Unknown station type")
+		return APIStation{}, false
+	}
+
+	return station, true
+}
+
+func (cli *CLI) migrateBindings(from string, to string) error {
+	bindings := client.self().Bindings
+
+	for _, binding := range bindings {
+		if binding.StationUser != client.APIClient.ID || binding.StationID != from {
+			continue
+		}
+
+		binding.StationID = to
+
+		err := client.APIClient.CreateBinding(binding)
+		if err != nil {
+			return err
+		}
+
+		client.setBinding(binding)
+
+		fmt.Printf("Bound station %s to %s\n", to, getInGameStationName(binding.ID))
+	}
+
+	return nil
+}
+
+func (cli *CLI) phaseCompleter(split []string) ([]prompt.Suggest, int) {
+	var s []prompt.Suggest
+
+	switch {
+	case len(split) == 2:
+		s = append(s, prompt.Suggest{Text: "set", Description: "Sets the station for an in-game station during a phase"})
+		s = append(s, prompt.Suggest{Text: "clear", Description: "Removes a phase rule"})
+	case len(split) == 3:
+		for _, phase := range matchPhases {
+			s = append(s, prompt.Suggest{Text: string(phase)})
+		}
+	case len(split) == 4 && split[1] == "set":
+		for _, station := range client.self().Stations {
+			s = append(s, prompt.Suggest{Text: station.ID})
+		}
+	case len(split) >= 4:
+		index := 3
+		if split[1] == "set" {
+			index = 4
+		}
+
+		for _, station := range inGameStations {
+			s = append(s, prompt.Suggest{Text: station.Name})
+		}
+
+		return s, index
+	}
+
+	return s, len(split) - 1
+}
+
+func (cli *CLI) createCmd(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: create <id> <type>")
+		return
+	}
+
+	if _, ok := client.self().Stations[args[0]]; ok {
+		fmt.Println("Station already exists")
+		return
+	}
+
+	station, ok := stationFromArgs(CreateCmd, args)
+	if !ok {
 		return
 	}
 
@@ -146,18 +389,122 @@ func (cli *CLI) createCmd(args []string) {
 		return
 	}
 
-	client.Users[client.APIClient.ID].Stations[station.ID] = station
+	client.setStation(station)
 
 	fmt.Printf("Successfully created station %s\n", station.ID)
 }
 
+func (cli *CLI) updateCmd(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: update <id> <type>")
+		return
+	}
+
+	if _, ok := client.self().Stations[args[0]]; !ok {
+		fmt.Println("Station not found")
+		return
+	}
+
+	station, ok := stationFromArgs(UpdateCmd, args)
+	if !ok {
+		return
+	}
+
+	err := client.APIClient.CreateStation(station)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	client.setStation(station)
+
+	fmt.Printf("Successfully updated station %s\n", station.ID)
+}
+
+func (cli *CLI) cloneCmd(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: clone <station> <new id>")
+		return
+	}
+
+	station, ok := client.self().Stations[args[0]]
+	if !ok {
+		fmt.Println("Station not found")
+		return
+	}
+
+	if _, ok := client.self().Stations[args[1]]; ok {
+		fmt.Println("Station already exists")
+		return
+	}
+
+	station.ID = args[1]
+
+	err := client.APIClient.CreateStation(station)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	client.setStation(station)
+
+	fmt.Printf("Cloned station %s to %s\n", args[0], station.ID)
+}
+
+func (cli *CLI) renameCmd(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: rename <station> <new id>")
+		return
+	}
+
+	station, ok := client.self().Stations[args[0]]
+	if !ok {
+		fmt.Println("Station not found")
+		return
+	}
+
+	if _, ok := client.self().Stations[args[1]]; ok {
+		fmt.Println("Station already exists")
+		return
+	}
+
+	renamed := station
+	renamed.ID = args[1]
+
+	err := client.APIClient.CreateStation(renamed)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	client.setStation(renamed)
+
+	err = cli.migrateBindings(station.ID, renamed.ID)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Printf("Station %s was kept as not every binding could be moved\n", station.ID)
+
+		return
+	}
+
+	err = client.APIClient.DeleteStation(station)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	client.deleteStation(station.ID)
+
+	fmt.Printf("Renamed station %s to %s\n", station.ID, renamed.ID)
+}
+
 func (cli *CLI) playCmd(args []string) {
 	if len(args) < 2 {
 		fmt.Println("Usage: play <station> <song>")
 		return
 	}
 
-	station, ok := client.Users[client.APIClient.ID].Stations[args[0]]
+	station, ok := client.self().Stations[args[0]]
 	if !ok {
 		fmt.Println("Station not found")
 		return
@@ -198,7 +545,7 @@ func (cli *CLI) deleteCmd(args []string) {
 		return
 	}
 
-	station, ok := client.Users[client.APIClient.ID].Stations[args[0]]
+	station, ok := client.self().Stations[args[0]]
 	if !ok {
 		fmt.Println("Station not found")
 		return
@@ -212,24 +559,64 @@ func (cli *CLI) deleteCmd(args []string) {
 		return
 	}
 
-	delete(client.Users[client.APIClient.ID].Stations, station.ID)
+	client.deleteStation(station.ID)
 
-	for i, binding := range client.Users[client.APIClient.ID].Bindings {
+	for i, binding := range client.self().Bindings {
 		if binding.StationUser == client.APIClient.ID && binding.StationID == station.ID {
-			delete(client.Users[client.APIClient.ID].Bindings, i)
+			client.deleteBinding(i)
+
+			fmt.Printf("Unbound station %s\n", getInGameStationName(binding.ID))
+		}
+	}
+
+	fmt.Printf("Successfully deleted station %s\n", station.ID)
+}
+
+// localFlag strips a leading --local from args, reporting whether it was there
+func localFlag(args []string) ([]string, bool) {
+	if len(args) > 0 && args[0] == "--local" {
+		return args[1:], true
+	}
+
+	return args, false
+}
+
+// bindTarget finds the station to bind to from either one of our station IDs or user:station for someone else's
+func (cli *CLI) bindTarget(target string) (string, APIStation, bool) {
+	split := strings.SplitN(target, ":", 2)
+	if len(split) == 1 || split[0] == client.APIClient.ID {
+		station, ok := client.self().Stations[split[len(split)-1]]
+		if !ok {
+			fmt.Println("Invalid station")
 		}
+
+		return client.APIClient.ID, station, ok
+	}
+
+	user, err := client.APIClient.GetUser(split[0])
+	if err != nil {
+		fmt.Println(err)
+		return "", APIStation{}, false
+	}
+
+	station, ok := user.Stations[split[1]]
+	if !ok {
+		fmt.Println("Invalid station")
 	}
+
+	return split[0], station, ok
 }
 
 func (cli *CLI) bindCmd(args []string) {
+	args, local := localFlag(args)
+
 	if len(args) < 2 {
-		fmt.Println("Usage: bind <station> <in-game station>")
+		fmt.Println("Usage: bind [--local] [<user>:]<station> <in-game station>")
 		return
 	}
 
-	station, ok := client.Users[client.APIClient.ID].Stations[args[0]]
+	stationUser, station, ok := cli.bindTarget(args[0])
 	if !ok {
-		fmt.Println("Invalid station")
 		return
 	}
 
@@ -242,19 +629,31 @@ func (cli *CLI) bindCmd(args []string) {
 
 	binding := APIBinding{
 		ID:          inGameStation.ID,
-		StationUser: client.APIClient.ID,
+		StationUser: stationUser,
 		StationID:   station.ID,
 	}
 
+	if local {
+		err := localBindings.Set(binding)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fmt.Printf("Bound station %s to %s locally\n", bindingName(binding), inGameStation.Name)
+
+		return
+	}
+
 	err := client.APIClient.CreateBinding(binding)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	client.Users[client.APIClient.ID].Bindings[binding.ID] = binding
+	client.setBinding(binding)
 
-	fmt.Printf("Bound station %s to %s\n", station.ID, inGameStation.Name)
+	fmt.Printf("Bound station %s to %s\n", bindingName(binding), inGameStation.Name)
 }
 
 func (cli *CLI) bindAllCmd(args []string) {
@@ -263,7 +662,7 @@ func (cli *CLI) bindAllCmd(args []string) {
 		return
 	}
 
-	station, ok := client.Users[client.APIClient.ID].Stations[args[0]]
+	station, ok := client.self().Stations[args[0]]
 	if !ok {
 		fmt.Println("Invalid station")
 		return
@@ -282,25 +681,38 @@ func (cli *CLI) bindAllCmd(args []string) {
 			return
 		}
 
-		client.Users[client.APIClient.ID].Bindings[binding.ID] = binding
+		client.setBinding(binding)
 
 		fmt.Printf("Bound station %s to %s\n", station.ID, inGameStation.Name)
 	}
 }
 
-func (cli *CLI) bindsCmd(_ []string) {
-	for _, station := range inGameStations {
-		if _, ok := client.Users[client.APIClient.ID].Bindings[station.ID]; ok {
-			fmt.Printf("%s -> %s\n", station.Name, client.Users[client.APIClient.ID].Bindings[station.ID].StationID)
-		} else {
+// bindingName is the station a binding plays, with the user in front when it isn't one of ours
+func bindingName(binding APIBinding) string {
+	if binding.StationUser == client.APIClient.ID {
+		return binding.StationID
+	}
+
+	return binding.StationUser + ":" + binding.StationID
+}
+
+func (cli *CLI) bindsCmd(_ []string) {
+	for _, station := range inGameStations {
+		if binding, ok := localBindings.Get(station.ID); ok {
+			fmt.Printf("%s -> %s (local)\n", station.Name, bindingName(binding))
+		} else if binding, ok := client.self().Bindings[station.ID]; ok {
+			fmt.Printf("%s -> %s\n", station.Name, bindingName(binding))
+		} else {
 			fmt.Printf("%s -> %s\n", station.Name, "Default")
 		}
 	}
 }
 
 func (cli *CLI) unbindCmd(args []string) {
+	args, local := localFlag(args)
+
 	if len(args) == 0 {
-		fmt.Println("Usage: unbind <in-game station>")
+		fmt.Println("Usage: unbind [--local] <in-game station>")
 		return
 	}
 
@@ -308,14 +720,27 @@ func (cli *CLI) unbindCmd(args []string) {
 
 	inGameStation, ok := getInGameStationByName(name)
 
-	if ok {
+	if ok && local {
+		deleted, err := localBindings.Delete(inGameStation.ID)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		if !deleted {
+			fmt.Println("No local binding for that in-game station")
+			return
+		}
+
+		fmt.Printf("Unbound station %s locally\n", inGameStation.Name)
+	} else if ok {
 		err := client.APIClient.DeleteBinding(APIBinding{ID: inGameStation.ID})
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
 
-		delete(client.Users[client.APIClient.ID].Bindings, inGameStation.ID)
+		client.deleteBinding(inGameStation.ID)
 
 		fmt.Printf("Unbound station %s\n", inGameStation.Name)
 	} else {
@@ -323,6 +748,759 @@ func (cli *CLI) unbindCmd(args []string) {
 	}
 }
 
+func (cli *CLI) exportCmd(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: export <file>")
+		return
+	}
+
+	file := strings.Join(args, " ")
+
+	err := saveProfile(file, exportProfile(client.self(), client.APIClient.ID))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("Exported stations and bindings to %s\n", file)
+}
+
+func (cli *CLI) importCmd(args []string) {
+	apply := len(args) > 0 && args[0] == "-y"
+	if apply {
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		fmt.Println("Usage: import [-y] <file>")
+		return
+	}
+
+	profile, err := loadProfile(strings.Join(args, " "))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	actions, err := planProfile(client.self(), client.APIClient.ID, profile, false)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if len(actions) == 0 {
+		fmt.Println("Nothing to import, everything is up to date")
+		return
+	}
+
+	for _, action := range actions {
+		fmt.Println(action)
+	}
+
+	if !apply {
+		fmt.Println("Run import -y to apply these changes")
+
+		return
+	}
+
+	// The plan is carried out on a copy that replaces our stations once it's done, even if it's only done in part
+	user := client.self().Copy()
+
+	err = executePlan(&client.APIClient, user, actions)
+
+	client.setSelf(user)
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("Import complete")
+}
+
+func (cli *CLI) applyCmd(args []string) {
+	dryRun := len(args) > 0 && args[0] == "-n"
+	if dryRun {
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		fmt.Println("Usage: apply [-n] <file>")
+		return
+	}
+
+	user, err := applyProfile(&client.APIClient, strings.Join(args, " "), dryRun)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	if user.Stations != nil {
+		client.setSelf(user)
+	}
+}
+
+func (cli *CLI) catalogueCmd(args []string) {
+	if len(args) > 0 && args[0] == "refresh" {
+		updated, err := refreshCatalogue(&client.APIClient)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		if !updated {
+			fmt.Printf("In-game stations are up to date (version %d)\n", catalogue.Version)
+			return
+		}
+
+		fmt.Printf("Updated in-game stations to version %d\n", catalogue.Version)
+	}
+
+	for _, station := range inGameStations {
+		line := station.Name
+
+		if len(station.Aliases) > 0 {
+			line += " (" + strings.Join(station.Aliases, ", ") + ")"
+		}
+
+		if seasons := station.Seasons(); seasons != "" {
+			line += " [" + seasons + "]"
+		}
+
+		fmt.Println(line)
+	}
+}
+
+func (cli *CLI) discoveredCmd(args []string) {
+	if len(args) > 0 && args[0] == "name" {
+		if len(args) < 3 {
+			fmt.Println("Usage: discovered name <id> <name>")
+			return
+		}
+
+		name := strings.Join(args[2:], " ")
+
+		if station, ok := getInGameStationByName(name); ok && station.ID != args[1] {
+			fmt.Println("An in-game station with that name already exists")
+			return
+		}
+
+		err := discovery.Name(args[1], name)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		setCatalogue(catalogue)
+
+		fmt.Printf("Named in-game station %s %s\n", args[1], name)
+
+		return
+	}
+
+	stations := discovery.List()
+	if len(stations) == 0 {
+		fmt.Println("No in-game stations have been seen yet, tune into a station in-game first")
+		return
+	}
+
+	for _, station := range stations {
+		name := "Unnamed"
+
+		if containsInGameStation(inGameStations, station.ID) {
+			name = getInGameStationName(station.ID)
+		}
+
+		fmt.Printf("%s -> %s (seen %d times, first %s, last %s)\n", station.ID, name,
+			station.Requests, station.FirstSeen.Format(time.RFC822), station.LastSeen.Format(time.RFC822))
+	}
+}
+
+func (cli *CLI) partyCmd(args []string) {
+	if len(args) > 0 {
+		if args[0] != PartyModeFollow && args[0] != PartyModeSelf {
+			fmt.Println("Usage: party [follow|self]")
+			return
+		}
+
+		// Party changes read the mode, so it's only changed between them
+		client.partyMu.Lock()
+		settings.PartyMode = args[0]
+		err := saveSettings()
+		client.partyMu.Unlock()
+
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fmt.Printf("Party mode set to %s\n", settings.PartyMode)
+
+		if party := client.currentParty(); party.ID != "" {
+			client.handlePartyChange(party)
+		}
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	party := client.PartyTracker.Party
+
+	fmt.Printf("State: %s\n", client.PartyTracker.State)
+
+	if party.ID != "" {
+		fmt.Printf("Party: %s\n", party.ID)
+	}
+
+	if party.Match != "" {
+		fmt.Printf("Match: %s\n", party.Match)
+	}
+
+	for _, member := range client.PartyStatus.Members {
+		name := member.ID
+
+		if member.ID == client.APIClient.ID {
+			name += " (you)"
+		}
+
+		if member.Leader {
+			name += " [leader]"
+		}
+
+		fmt.Printf("Running FNRadio: %s\n", name)
+	}
+
+	if client.BoundUser == client.APIClient.ID {
+		fmt.Println("Active stations: yours")
+	} else {
+		fmt.Printf("Active stations: %s\n", client.BoundUser)
+	}
+
+	if client.PartyStatus.Reason != "" {
+		fmt.Printf("Why: %s\n", client.PartyStatus.Reason)
+	}
+
+	fmt.Printf("Party mode: %s\n", settings.PartyMode)
+}
+
+func printPartyQueueEntry(entry PartyQueueEntry) {
+	if entry.Queued {
+		fmt.Printf("[%s] %s is queued on %s\n", entry.ID, entry.Source, entry.Station)
+	} else {
+		fmt.Printf("[%s] %s needs %d/%d votes to be queued on %s\n", entry.ID, entry.Source, entry.Votes, entry.Needed, entry.Station)
+	}
+}
+
+func (cli *CLI) partyPlayCmd(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: partyplay <station> <song>")
+		return
+	}
+
+	if client.currentParty().ID == "" {
+		fmt.Println("You aren't in a party")
+		return
+	}
+
+	entry, err := client.APIClient.PartyAddToQueue(args[0], strings.Join(args[1:], " "))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	printPartyQueueEntry(entry)
+}
+
+func (cli *CLI) partyQueueCmd(args []string) {
+	if len(args) >= 1 && args[0] == "vote" {
+		if len(args) < 2 {
+			fmt.Println("Usage: partyqueue vote <id>")
+			return
+		}
+
+		entry, err := client.APIClient.VotePartyQueue(args[1])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		printPartyQueueEntry(entry)
+
+		return
+	}
+
+	if len(args) >= 1 && args[0] == "policy" {
+		if len(args) < 2 || (args[1] != PartyQueuePolicyAll && args[1] != PartyQueuePolicyVote && args[1] != PartyQueuePolicyLeader) {
+			fmt.Println("Usage: partyqueue policy <all|vote|leader>")
+			return
+		}
+
+		err := client.APIClient.SetPartyQueuePolicy(args[1])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fmt.Printf("Party queue policy set to %s\n", args[1])
+
+		return
+	}
+
+	queue, err := client.APIClient.GetPartyQueue()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if queue.Leader != "" {
+		fmt.Printf("Party leader: %s\n", queue.Leader)
+	}
+
+	fmt.Printf("Queue policy: %s\n", queue.Policy)
+
+	if len(queue.Entries) == 0 {
+		fmt.Println("The party queue is empty")
+	}
+
+	for _, entry := range queue.Entries {
+		printPartyQueueEntry(entry)
+	}
+}
+
+func (cli *CLI) metricsCmd(_ []string) {
+	counts, matches, matchTime := client.Metrics.Snapshot()
+
+	fmt.Printf("Matches: %d (%s in matches)\n", matches, matchTime.Round(time.Second))
+
+	names := make([]string, 0, len(counts))
+
+	for name := range counts {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("%s: %d\n", name, counts[name])
+	}
+}
+
+func (cli *CLI) handleEvent(event GameEvent) {
+	cli.mu.Lock()
+	defer cli.mu.Unlock()
+
+	cli.status = event.To.String()
+}
+
+func (cli *CLI) livePrefix() (string, bool) {
+	cli.mu.Lock()
+	defer cli.mu.Unlock()
+
+	if cli.status == "" {
+		return "", false
+	}
+
+	return "[" + cli.status + "] > ", true
+}
+
+func (cli *CLI) phaseCmd(args []string) {
+	switch {
+	case len(args) >= 4 && args[0] == "set" && isMatchPhase(args[1]):
+		station, ok := client.self().Stations[args[2]]
+		if !ok {
+			fmt.Println("Invalid station")
+			return
+		}
+
+		inGameStation, ok := getInGameStationByName(strings.Join(args[3:], " "))
+		if !ok {
+			fmt.Println("In-game station not found")
+			return
+		}
+
+		err := phaseRules.Set(PhaseRule{
+			Phase:       MatchPhase(args[1]),
+			ID:          inGameStation.ID,
+			StationUser: client.APIClient.ID,
+			StationID:   station.ID,
+		})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fmt.Printf("%s will play %s during %s\n", inGameStation.Name, station.ID, args[1])
+	case len(args) >= 3 && args[0] == "clear" && isMatchPhase(args[1]):
+		inGameStation, ok := getInGameStationByName(strings.Join(args[2:], " "))
+		if !ok {
+			fmt.Println("In-game station not found")
+			return
+		}
+
+		cleared, err := phaseRules.Clear(MatchPhase(args[1]), inGameStation.ID)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		if !cleared {
+			fmt.Println("No rule for that phase and in-game station")
+			return
+		}
+
+		fmt.Printf("Cleared %s rule for %s\n", args[1], inGameStation.Name)
+	case len(args) == 0:
+		client.mu.Lock()
+		phase := client.PartyTracker.CurrentPhase()
+		client.mu.Unlock()
+
+		fmt.Printf("Current phase: %s\n", phase)
+
+		for _, rule := range phaseRules.List() {
+			fmt.Printf("%s: %s -> %s\n", rule.Phase, getInGameStationName(rule.ID), rule.StationID)
+		}
+	default:
+		fmt.Println("Usage: phase [set <phase> <station> <in-game station> | clear <phase> <in-game station>]")
+		fmt.Println("Phases: lobby, warmup, bus, match, endgame, victory")
+	}
+}
+
+func (cli *CLI) scheduleCmd(args []string) {
+	switch {
+	case len(args) >= 4 && args[0] == "add":
+		station, ok := client.self().Stations[args[1]]
+		if !ok {
+			fmt.Println("Invalid station")
+			return
+		}
+
+		inGameStation, ok := getInGameStationByName(strings.Join(args[3:], " "))
+		if !ok {
+			fmt.Println("In-game station not found")
+			return
+		}
+
+		err := scheduler.Add(ScheduleRule{
+			ID:          inGameStation.ID,
+			StationUser: client.APIClient.ID,
+			StationID:   station.ID,
+			When:        args[2],
+		})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		scheduler.Update(time.Now())
+
+		fmt.Printf("%s will play %s during %s\n", inGameStation.Name, station.ID, args[2])
+	case len(args) == 2 && args[0] == "remove":
+		number, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Println("Usage: schedule remove <number>")
+			return
+		}
+
+		rule, err := scheduler.Remove(number - 1)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		scheduler.Update(time.Now())
+
+		fmt.Printf("Removed schedule for %s\n", getInGameStationName(rule.ID))
+	case len(args) == 0:
+		for i, rule := range scheduler.List() {
+			active := ""
+
+			if binding, ok := scheduler.Active(rule.ID); ok && binding == rule.Binding() {
+				active = " (active)"
+			}
+
+			fmt.Printf("%d. %s -> %s during %s%s\n", i+1, getInGameStationName(rule.ID), rule.StationID, rule.When, active)
+		}
+	default:
+		fmt.Println("Usage: schedule [add <station> <when> <in-game station> | remove <number>]")
+		fmt.Println("<when> is a comma separated list of months (dec), weekdays (sat) and a time range (22:00-06:00)")
+	}
+}
+
+func (cli *CLI) whyPlaysCmd(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: whyplays <in-game station>")
+		return
+	}
+
+	inGameStation, ok := getInGameStationByName(strings.Join(args, " "))
+	if !ok {
+		fmt.Println("In-game station not found")
+		return
+	}
+
+	client.mu.Lock()
+	resolution := client.resolve(inGameStation.ID)
+	client.mu.Unlock()
+
+	for _, step := range resolution.Steps {
+		fmt.Println("  " + step)
+	}
+
+	if resolution.Layer == "" {
+		fmt.Printf("%s plays Fortnite's own audio\n", inGameStation.Name)
+		return
+	}
+
+	fmt.Printf("%s plays %s:%s (%s)\n", inGameStation.Name, resolution.Binding.StationUser, resolution.Binding.StationID, resolution.Layer)
+}
+
+func printPublicStation(station APIPublicStation) {
+	fmt.Printf("%s:%s (%s)\n", station.User, station.ID, strings.TrimSpace(station.Type+" "+station.Source))
+}
+
+func (cli *CLI) browseCmd(args []string) {
+	if len(args) > 0 {
+		stations, err := client.APIClient.SearchStations(strings.Join(args, " "))
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		if len(stations) == 0 {
+			fmt.Println("No public stations found")
+		}
+
+		for _, station := range stations {
+			printPublicStation(station)
+		}
+
+		return
+	}
+
+	users := following.List()
+	if len(users) == 0 {
+		fmt.Println("Usage: browse <query>, or follow someone to list their stations here")
+		return
+	}
+
+	for _, id := range users {
+		user, err := client.APIClient.GetUser(id)
+		if err != nil {
+			fmt.Printf("%s: %s\n", id, err)
+			continue
+		}
+
+		stations := make([]APIPublicStation, 0, len(user.Stations))
+
+		for _, station := range user.Stations {
+			stations = append(stations, APIPublicStation{User: id, APIStation: station})
+		}
+
+		sort.Slice(stations, func(i, j int) bool {
+			return stations[i].ID < stations[j].ID
+		})
+
+		for _, station := range stations {
+			printPublicStation(station)
+		}
+	}
+}
+
+func (cli *CLI) followCmd(args []string) {
+	if len(args) != 1 {
+		for _, user := range following.List() {
+			fmt.Println(user)
+		}
+
+		return
+	}
+
+	_, err := client.APIClient.GetUser(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	added, err := following.Add(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if !added {
+		fmt.Printf("Already following %s\n", args[0])
+		return
+	}
+
+	fmt.Printf("Following %s, their stations show up in browse\n", args[0])
+}
+
+func (cli *CLI) unfollowCmd(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: unfollow <user>")
+		return
+	}
+
+	removed, err := following.Remove(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if !removed {
+		fmt.Printf("Not following %s\n", args[0])
+		return
+	}
+
+	fmt.Printf("Stopped following %s\n", args[0])
+}
+
+func (cli *CLI) accountCmd(args []string) {
+	switch {
+	case len(args) >= 2 && (args[0] == "name" || args[0] == "handle"):
+		var update APIAccount
+
+		if args[0] == "name" {
+			update.Name = strings.Join(args[1:], " ")
+		} else {
+			update.Handle = args[1]
+		}
+
+		account, err := client.APIClient.UpdateAccount(update)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		printAccount(account)
+	case len(args) == 1 && args[0] == "rotate":
+		api := client.APIClient
+
+		err := api.RotateSecret()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		client.mu.Lock()
+		client.APIClient = api
+		client.mu.Unlock()
+
+		fmt.Println("Your secret has been replaced, other computers using this account need to log in again")
+	case len(args) == 3 && args[0] == "login":
+		err := client.useAccount(APIClient{ID: args[1], Secret: args[2]})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fmt.Printf("Logged in as %s\n", args[1])
+	case len(args) == 1 && args[0] == "link":
+		pairing, err := client.APIClient.CreatePairing()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		printPairing(pairing)
+	case len(args) == 2 && args[0] == "join":
+		api, err := JoinPairing(args[1])
+		if err == nil {
+			err = client.useAccount(api)
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fmt.Printf("Logged in as %s\n", api.ID)
+	case len(args) >= 1 && args[0] == "reset":
+		if len(args) < 2 || args[1] != "-y" {
+			fmt.Println("This deletes your account with all of its stations and bindings, run account reset -y to go ahead")
+			return
+		}
+
+		err := client.APIClient.DeleteAccount()
+		if err == nil {
+			err = credentials.Delete(apiCredentialName())
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		api := APIClient{}
+
+		err = api.Register()
+		if err == nil {
+			err = client.useAccount(api)
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fmt.Printf("Started a new account %s\n", api.ID)
+	case len(args) == 0:
+		account, err := client.APIClient.GetAccount()
+		if err != nil {
+			fmt.Printf("ID: %s\n", client.APIClient.ID)
+			fmt.Println(err)
+
+			return
+		}
+
+		printAccount(account)
+	default:
+		fmt.Println("Usage: account [name <name> | handle <handle> | rotate | login <id> <secret> | link | join <code> | reset]")
+	}
+}
+
+func printPairing(pairing APIPairing) {
+	code, err := qrcode.New(pairing.Code, qrcode.Medium)
+	if err == nil {
+		fmt.Print(code.ToSmallString(false))
+	}
+
+	fmt.Printf("Run account join %s on your other computer (or fnradio join %s before FNRadio has been started there)\n", pairing.Code, pairing.Code)
+	fmt.Printf("The code works once and expires at %s\n", pairing.ExpiresAt.Local().Format("15:04"))
+}
+
+func (cli *CLI) certCmd(args []string) {
+	certificate, err := certCommand(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if certificate != nil {
+		if err := client.CertCache.SetCA(certificate); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	// Without the certificate the proxy can't do anything, so there's no point carrying on
+	if len(args) > 0 && args[0] == "uninstall" {
+		client.Destroy()
+		os.Exit(0)
+	}
+}
+
+func printAccount(account APIAccount) {
+	fmt.Printf("ID: %s\n", account.ID)
+
+	if account.Name != "" {
+		fmt.Printf("Name: %s\n", account.Name)
+	}
+
+	if account.Handle != "" {
+		fmt.Printf("Handle: %s\n", account.Handle)
+	}
+}
+
 func (cli *CLI) execute(t string) {
 	split := strings.Split(t, " ")
 
@@ -341,6 +1519,46 @@ func (cli *CLI) execute(t string) {
 		cli.unbindCmd(split[1:])
 	case BindsCmd:
 		cli.bindsCmd(split[1:])
+	case RenameCmd:
+		cli.renameCmd(split[1:])
+	case CloneCmd:
+		cli.cloneCmd(split[1:])
+	case UpdateCmd:
+		cli.updateCmd(split[1:])
+	case ExportCmd:
+		cli.exportCmd(split[1:])
+	case ImportCmd:
+		cli.importCmd(split[1:])
+	case ApplyCmd:
+		cli.applyCmd(split[1:])
+	case CatalogueCmd:
+		cli.catalogueCmd(split[1:])
+	case DiscoveredCmd:
+		cli.discoveredCmd(split[1:])
+	case PartyCmd:
+		cli.partyCmd(split[1:])
+	case PartyPlayCmd:
+		cli.partyPlayCmd(split[1:])
+	case PartyQueueCmd:
+		cli.partyQueueCmd(split[1:])
+	case MetricsCmd:
+		cli.metricsCmd(split[1:])
+	case PhaseCmd:
+		cli.phaseCmd(split[1:])
+	case ScheduleCmd:
+		cli.scheduleCmd(split[1:])
+	case WhyPlaysCmd:
+		cli.whyPlaysCmd(split[1:])
+	case BrowseCmd:
+		cli.browseCmd(split[1:])
+	case FollowCmd:
+		cli.followCmd(split[1:])
+	case UnfollowCmd:
+		cli.unfollowCmd(split[1:])
+	case AccountCmd:
+		cli.accountCmd(split[1:])
+	case CertCmd:
+		cli.certCmd(split[1:])
 	default:
 		fmt.Println("Unknown command")
 	}
@@ -349,7 +1567,9 @@ func (cli *CLI) execute(t string) {
 func setupCLI() {
 	cli := &CLI{}
 
-	p := prompt.New(cli.execute, cli.completer, prompt.OptionPrefix("> "))
+	client.Events.Subscribe(cli.handleEvent)
+
+	p := prompt.New(cli.execute, cli.completer, prompt.OptionPrefix("> "), prompt.OptionLivePrefix(cli.livePrefix))
 
 	for {
 		p.Run()
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const settingsFile = "settings.json"
+
+const (
+	PartyModeFollow = "follow"
+	PartyModeSelf   = "self"
+)
+
+type Settings struct {
+	PartyMode string `json:"party_mode,omitempty"`
+	GameLog   string `json:"game_log,omitempty"`
+
+	EventsFile    string `json:"events_file,omitempty"`
+	EventsWebhook string `json:"events_webhook,omitempty"`
+
+	BindingOrder   []string `json:"binding_order,omitempty"`
+	DefaultProfile string   `json:"default_profile,omitempty"`
+
+	SecretStore string `json:"secret_store,omitempty"`
+}
+
+var settings = Settings{
+	PartyMode: PartyModeFollow,
+}
+
+func loadSettings() error {
+	err := loadConfig(settingsFile, &settings)
+	if err != nil {
+		return err
+	}
+
+	// binding_order is used as written, layers it leaves out are skipped on purpose
+	order := settings.BindingOrder[:0]
+
+	for _, layer := range settings.BindingOrder {
+		if !isBindingLayer(layer) {
+			log.Printf("WARN: Ignoring unknown binding layer %q in binding_order\n", layer)
+			continue
+		}
+
+		order = append(order, layer)
+	}
+
+	settings.BindingOrder = order
+
+	return nil
+}
+
+func saveSettings() error {
+	return saveConfig(settingsFile, settings)
+}
+
+func configPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir = filepath.Join(dir, "FNRadio")
+
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, name), nil
+}
+
+// loadConfig reads a JSON file from the config directory into v, a missing file leaves v untouched
+func loadConfig(name string, v interface{}) error {
+	file, err := configPath(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func saveConfig(name string, v interface{}) error {
+	file, err := configPath(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, data, 0600)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// tempConfigDir points the config directory somewhere empty for the rest of the test
+func tempConfigDir(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	t.Setenv("AppData", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+}
+
+func writeConfig(t *testing.T, name string, data string) {
+	t.Helper()
+
+	file, err := configPath(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadSettingsBindingOrder(t *testing.T) {
+	tests := []struct {
+		settings string
+		want     []string
+	}{
+		{`{}`, nil},
+		{`{"binding_order": ["own", "leader", "default"]}`, []string{"own", "leader", "default"}},
+		{`{"binding_order": ["own", "local", "leader"]}`, []string{"own", "local", "leader"}},
+		{`{"binding_order": ["own", "bogus", "leader"]}`, []string{"own", "leader"}},
+	}
+
+	oldSettings := settings
+
+	t.Cleanup(func() {
+		settings = oldSettings
+	})
+
+	for _, test := range tests {
+		tempConfigDir(t)
+		writeConfig(t, settingsFile, test.settings)
+
+		settings = Settings{}
+
+		if err := loadSettings(); err != nil {
+			t.Fatalf("%s: %v", test.settings, err)
+		}
+
+		if !reflect.DeepEqual(settings.BindingOrder, test.want) {
+			t.Errorf("%s: got %q, want %q", test.settings, settings.BindingOrder, test.want)
+		}
+	}
+}
+
+func TestLoadLocalBindingsNull(t *testing.T) {
+	tempConfigDir(t)
+	writeConfig(t, localBindingsFile, `null`)
+
+	oldBindings := localBindings
+
+	t.Cleanup(func() {
+		localBindings = oldBindings
+	})
+
+	localBindings = &LocalBindings{Bindings: map[string]APIBinding{}}
+
+	if err := loadLocalBindings(); err != nil {
+		t.Fatal(err)
+	}
+
+	binding := APIBinding{ID: "icon", StationUser: "user-a", StationID: "lofi"}
+
+	if err := localBindings.Set(binding); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := localBindings.Get("icon"); !ok || got != binding {
+		t.Errorf("got %+v, want %+v", got, binding)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+const (
+	SecretStoreKeyring = "keyring"
+	SecretStoreFile    = "file"
+
+	sslPrivateKeyCredential  = "SSLPrivateKey"
+	sslCertificateCredential = "SSLCertificate"
+)
+
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// CredentialStore keeps secrets such as the API credentials and the CA private key between runs
+type CredentialStore interface {
+	Load(name string) ([]byte, error)
+	Save(name string, value []byte) error
+	Delete(name string) error
+}
+
+var credentials CredentialStore = &fileCredentials{}
+
+// fallbackCredentials reads secrets it doesn't have from a second store and moves them over, so switching secret_store
+// or a Credential Manager that couldn't be written to for one run doesn't leave us without them
+type fallbackCredentials struct {
+	primary   CredentialStore
+	secondary CredentialStore
+}
+
+func (f fallbackCredentials) Load(name string) ([]byte, error) {
+	value, err := f.primary.Load(name)
+	if !errors.Is(err, ErrCredentialNotFound) || f.secondary == nil {
+		return value, err
+	}
+
+	value, err = f.secondary.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.primary.Save(name, value)
+	if err == nil {
+		err = f.secondary.Delete(name)
+	}
+
+	if err != nil {
+		log.Println("WARN: Failed to move " + name + " to where secrets are kept now: " + err.Error())
+	}
+
+	return value, nil
+}
+
+func (f fallbackCredentials) Save(name string, value []byte) error {
+	return f.primary.Save(name, value)
+}
+
+func (f fallbackCredentials) Delete(name string) error {
+	err := f.primary.Delete(name)
+	if err != nil || f.secondary == nil {
+		return err
+	}
+
+	return f.secondary.Delete(name)
+}
+
+// setupCredentials picks where secrets are kept, the Credential Manager unless the secret_store setting asks for the
+// encrypted file or it isn't available, then moves over any secrets older versions left in the registry. Secrets are
+// still read from the other store while it can be read, see fallbackCredentials.
+func setupCredentials() error {
+	var keyring CredentialStore = keyringCredentials{}
+
+	store := fallbackCredentials{primary: &fileCredentials{}}
+
+	if settings.SecretStore != SecretStoreFile {
+		err := keyringWorks()
+		if err == nil {
+			store = fallbackCredentials{primary: keyring, secondary: store.primary}
+		} else {
+			log.Println("WARN: The Credential Manager can't be used (" + err.Error() + "), secrets will be kept in an encrypted file")
+		}
+	}
+
+	// Reading from the Credential Manager can work when writing to it doesn't
+	if store.secondary == nil && keyringReadable() {
+		store.secondary = keyring
+	}
+
+	credentials = store
+
+	return migrateRegistrySecrets(credentials)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// brokenCredentials fails every call, like a store that can't be decrypted
+type brokenCredentials struct{}
+
+func (brokenCredentials) Load(string) ([]byte, error) { return nil, errors.New("broken") }
+func (brokenCredentials) Save(string, []byte) error   { return errors.New("broken") }
+func (brokenCredentials) Delete(string) error         { return errors.New("broken") }
+
+func TestFallbackCredentialsMovesSecrets(t *testing.T) {
+	primary, secondary := memoryCredentials{}, memoryCredentials{"secret": []byte("value")}
+	store := fallbackCredentials{primary: primary, secondary: secondary}
+
+	value, err := store.Load("secret")
+	if err != nil || string(value) != "value" {
+		t.Fatalf("got %q, %v", value, err)
+	}
+
+	if string(primary["secret"]) != "value" || len(secondary) != 0 {
+		t.Errorf("secret wasn't moved: primary %q, secondary %q", primary, secondary)
+	}
+
+	if _, err := store.Load("missing"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("missing secret: got %v, want ErrCredentialNotFound", err)
+	}
+
+	secondary["other"] = []byte("value")
+
+	if err := store.Delete("other"); err != nil || len(secondary) != 0 {
+		t.Errorf("delete left %q in the other store: %v", secondary, err)
+	}
+}
+
+func TestFallbackCredentialsReportsErrors(t *testing.T) {
+	store := fallbackCredentials{primary: memoryCredentials{}, secondary: brokenCredentials{}}
+
+	// A store that can't be read mustn't look like one without the secret
+	if _, err := store.Load("secret"); err == nil || errors.Is(err, ErrCredentialNotFound) {
+		t.Errorf("got %v, want the other store's error", err)
+	}
+}
+
+// fakeRegisterServer counts the users registered through it
+func fakeRegisterServer(t *testing.T) *int {
+	t.Helper()
+
+	registered := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/users" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		registered++
+
+		_, _ = w.Write([]byte(`{"id": "new-user", "secret": "new-secret"}`))
+	}))
+
+	oldRoot := APIRoot
+	APIRoot = server.URL
+
+	t.Cleanup(func() {
+		APIRoot = oldRoot
+
+		server.Close()
+	})
+
+	return &registered
+}
+
+func TestSetupRegistersFirstTime(t *testing.T) {
+	tempConfigDir(t)
+	fakeStores(t)
+
+	registered := fakeRegisterServer(t)
+
+	api := APIClient{}
+	api.Setup()
+
+	if *registered != 1 || api.ID != "new-user" {
+		t.Fatalf("registered %d users, now %+v", *registered, api)
+	}
+
+	// The next start finds the saved credentials
+	api = APIClient{}
+	api.Setup()
+
+	if *registered != 1 || api.ID != "new-user" {
+		t.Errorf("registered %d users, now %+v", *registered, api)
+	}
+}
+
+func TestSetupKeepsMissingAccount(t *testing.T) {
+	tempConfigDir(t)
+
+	registered := fakeRegisterServer(t)
+
+	if err := rememberAccount("old-user"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Credentials were saved before, but the store in use now doesn't have them
+	fakeStores(t)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Setup to fail")
+		}
+
+		if *registered != 0 {
+			t.Errorf("registered %d users over the missing account", *registered)
+		}
+	}()
+
+	api := APIClient{}
+	api.Setup()
+}
+
+func TestSetupSSLStoreError(t *testing.T) {
+	trust, _ := fakeStores(t)
+
+	credentials = brokenCredentials{}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected setupSSL to fail")
+		}
+
+		if len(trust) != 0 {
+			t.Errorf("%d certificates were trusted", len(trust))
+		}
+	}()
+
+	setupSSL()
+}
+
+func TestSetupSSLMissingKey(t *testing.T) {
+	trust, creds := fakeStores(t)
+
+	if _, err := createCACertificate(); err != nil {
+		t.Fatal(err)
+	}
+
+	old := currentCA(t)
+
+	delete(creds, sslPrivateKeyCredential)
+
+	setupSSL()
+
+	current := currentCA(t)
+
+	if current.Equal(old) || trust[string(old.Raw)] || !trust[string(current.Raw)] {
+		t.Errorf("the CA without a key wasn't replaced, %d certificates are trusted", len(trust))
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const discoveredFile = "discovered.json"
+
+// discoverySaveDelay is how long requests are counted before they are saved, the game makes one for every station
+// each time it loads, so saving on every request would write the file dozens of times in a row
+const discoverySaveDelay = 10 * time.Second
+
+type DiscoveredStation struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Requests  int       `json:"requests"`
+}
+
+// Discovery records every in-game station ID seen in intercepted requests, so stations added in a new season can be
+// named and bound before the catalogue knows about them
+type Discovery struct {
+	mu       sync.Mutex
+	Stations map[string]*DiscoveredStation
+
+	// dirty is set when there are requests that haven't been saved, saveTimer saves them once it fires
+	dirty     bool
+	saveTimer *time.Timer
+}
+
+var discovery = &Discovery{Stations: map[string]*DiscoveredStation{}}
+
+func loadDiscovery() error {
+	discovery.mu.Lock()
+	defer discovery.mu.Unlock()
+
+	var stations []*DiscoveredStation
+
+	err := loadConfig(discoveredFile, &stations)
+	if err != nil {
+		return err
+	}
+
+	for _, station := range stations {
+		discovery.Stations[station.ID] = station
+	}
+
+	return nil
+}
+
+func (d *Discovery) save() error {
+	d.dirty = false
+
+	stations := make([]*DiscoveredStation, 0, len(d.Stations))
+
+	for _, station := range d.Stations {
+		stations = append(stations, station)
+	}
+
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].FirstSeen.Before(stations[j].FirstSeen)
+	})
+
+	return saveConfig(discoveredFile, stations)
+}
+
+// Observe records a request for id, returning true the first time id is seen. It's saved a little later with any
+// other requests that come in by then, see Flush.
+func (d *Discovery) Observe(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+
+	station, ok := d.Stations[id]
+	if !ok {
+		station = &DiscoveredStation{
+			ID:        id,
+			FirstSeen: now,
+		}
+
+		d.Stations[id] = station
+	}
+
+	station.LastSeen = now
+	station.Requests++
+
+	d.dirty = true
+
+	if d.saveTimer == nil {
+		d.saveTimer = time.AfterFunc(discoverySaveDelay, func() {
+			err := d.Flush()
+			if err != nil {
+				_ = client.Logger.Output(2, "Failed to save discovered stations: "+err.Error())
+			}
+		})
+	}
+
+	return !ok
+}
+
+// Flush saves any requests Observe hasn't saved yet
+func (d *Discovery) Flush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.saveTimer != nil {
+		d.saveTimer.Stop()
+		d.saveTimer = nil
+	}
+
+	if !d.dirty {
+		return nil
+	}
+
+	return d.save()
+}
+
+func (d *Discovery) Name(id string, name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	station, ok := d.Stations[id]
+	if !ok {
+		now := time.Now()
+
+		station = &DiscoveredStation{
+			ID:        id,
+			FirstSeen: now,
+			LastSeen:  now,
+		}
+
+		d.Stations[id] = station
+	}
+
+	station.Name = name
+
+	return d.save()
+}
+
+func (d *Discovery) List() []DiscoveredStation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stations := make([]DiscoveredStation, 0, len(d.Stations))
+
+	for _, station := range d.Stations {
+		stations = append(stations, *station)
+	}
+
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].LastSeen.After(stations[j].LastSeen)
+	})
+
+	return stations
+}
+
+// NamedStations returns the stations the user has named which the catalogue doesn't already contain
+func (d *Discovery) NamedStations(known []InGameStation) []InGameStation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var stations []InGameStation
+
+	for _, station := range d.Stations {
+		if station.Name == "" || containsInGameStation(known, station.ID) {
+			continue
+		}
+
+		stations = append(stations, InGameStation{ID: station.ID, Name: station.Name})
+	}
+
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].Name < stations[j].Name
+	})
+
+	return stations
+}
+
+func containsInGameStation(stations []InGameStation, id string) bool {
+	for _, station := range stations {
+		if station.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
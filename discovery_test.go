@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestDiscoverySavesOnFlush(t *testing.T) {
+	tempConfigDir(t)
+
+	d := &Discovery{Stations: map[string]*DiscoveredStation{}}
+
+	t.Cleanup(func() {
+		_ = d.Flush()
+	})
+
+	if !d.Observe("new-station") {
+		t.Error("first request wasn't reported as new")
+	}
+
+	if d.Observe("new-station") {
+		t.Error("second request was reported as new")
+	}
+
+	file, err := configPath(discoveredFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(file); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("requests were saved straight away: %v", err)
+	}
+
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved []DiscoveredStation
+
+	if err := loadConfig(discoveredFile, &saved); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(saved) != 1 || saved[0].ID != "new-station" || saved[0].Requests != 2 {
+		t.Errorf("saved %+v, want new-station with 2 requests", saved)
+	}
+}
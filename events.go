@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// GameEvent is a party tracker transition as published on the event bus, Replayed is set for events read from the
+// game log before FNRadio caught up with it
+type GameEvent struct {
+	PartyTransition
+	Time     time.Time
+	Replayed bool
+}
+
+// EventBus passes game events to every subscriber in the order they happen, handlers run on the publishing goroutine
+// so anything slow should hand events off to its own goroutine
+type EventBus struct {
+	mu       sync.Mutex
+	handlers []func(GameEvent)
+}
+
+func (bus *EventBus) Subscribe(handler func(GameEvent)) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.handlers = append(bus.handlers, handler)
+}
+
+func (bus *EventBus) Publish(event GameEvent) {
+	bus.mu.Lock()
+	handlers := bus.handlers
+	bus.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+type EventJSON struct {
+	Time     time.Time  `json:"time"`
+	Type     string     `json:"type"`
+	Message  string     `json:"message"`
+	Event    PartyEvent `json:"event"`
+	From     string     `json:"from"`
+	To       string     `json:"to"`
+	Party    Party      `json:"party"`
+	Replayed bool       `json:"replayed,omitempty"`
+}
+
+func (event GameEvent) JSON() EventJSON {
+	return EventJSON{
+		Time:     event.Time,
+		Type:     event.Event.Type(),
+		Message:  event.Event.String(),
+		Event:    event.Event,
+		From:     event.From.String(),
+		To:       event.To.String(),
+		Party:    event.New,
+		Replayed: event.Replayed,
+	}
+}
+
+// EventOutput writes events as JSON lines to a file and/or posts them to a webhook, in the background so a slow
+// webhook doesn't hold up party sync
+type EventOutput struct {
+	File    string
+	Webhook string
+	Logger  *log.Logger
+
+	queue chan GameEvent
+}
+
+const eventOutputQueueSize = 256
+
+func (output *EventOutput) Start(bus *EventBus) {
+	output.queue = make(chan GameEvent, eventOutputQueueSize)
+
+	go output.run()
+
+	bus.Subscribe(func(event GameEvent) {
+		select {
+		case output.queue <- event:
+		default:
+			_ = output.Logger.Output(2, "Dropped event "+event.Event.Type()+" as the event output is falling behind")
+		}
+	})
+}
+
+func (output *EventOutput) run() {
+	for event := range output.queue {
+		data, err := json.Marshal(event.JSON())
+		if err != nil {
+			_ = output.Logger.Output(2, "Failed to encode event: "+err.Error())
+			continue
+		}
+
+		if output.File != "" {
+			err = appendLine(output.File, data)
+			if err != nil {
+				_ = output.Logger.Output(2, "Failed to write event: "+err.Error())
+			}
+		}
+
+		if output.Webhook != "" {
+			err = postEvent(output.Webhook, data)
+			if err != nil {
+				_ = output.Logger.Output(2, "Failed to send event to webhook: "+err.Error())
+			}
+		}
+	}
+}
+
+func appendLine(file string, data []byte) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func postEvent(webhook string, data []byte) error {
+	httpClient := http.Client{Timeout: 10 * time.Second}
+
+	response, err := httpClient.Post(webhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.New("webhook responded with status code " + strconv.Itoa(response.StatusCode))
+	}
+
+	return nil
+}
+
+// EventMetrics counts game events for the metrics command
+type EventMetrics struct {
+	mu          sync.Mutex
+	Counts      map[string]int
+	Matches     int
+	MatchTime   time.Duration
+	matchJoined time.Time
+}
+
+func (metrics *EventMetrics) Handle(event GameEvent) {
+	if event.Replayed {
+		return
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	if metrics.Counts == nil {
+		metrics.Counts = map[string]int{}
+	}
+
+	metrics.Counts[event.Event.Type()]++
+
+	if event.To == PartyStateInMatch && event.From != PartyStateInMatch {
+		metrics.Matches++
+		metrics.matchJoined = event.Time
+	}
+
+	if event.From == PartyStateInMatch && event.To != PartyStateInMatch && !metrics.matchJoined.IsZero() {
+		metrics.MatchTime += event.Time.Sub(metrics.matchJoined)
+		metrics.matchJoined = time.Time{}
+	}
+}
+
+// Snapshot returns a copy of the counts along with match stats, including the match in progress
+func (metrics *EventMetrics) Snapshot() (map[string]int, int, time.Duration) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	counts := map[string]int{}
+
+	for name, count := range metrics.Counts {
+		counts[name] = count
+	}
+
+	matchTime := metrics.MatchTime
+
+	if !metrics.matchJoined.IsZero() {
+		matchTime += time.Since(metrics.matchJoined)
+	}
+
+	return counts, metrics.Matches, matchTime
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// trackedEvents runs a log fixture through a party tracker, returning the events that would be published
+func trackedEvents(t *testing.T, file string) []GameEvent {
+	t.Helper()
+
+	var tracker PartyTracker
+
+	var events []GameEvent
+
+	start := time.Date(2024, time.March, 9, 20, 0, 0, 0, time.UTC)
+
+	for i, event := range fixtureEvents(t, file) {
+		events = append(events, GameEvent{
+			PartyTransition: tracker.Apply(event),
+			Time:            start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	return events
+}
+
+func eventTypes(events []GameEvent) []string {
+	types := make([]string, len(events))
+
+	for i, event := range events {
+		types[i] = event.Event.Type()
+	}
+
+	return types
+}
+
+func TestEventBusOrder(t *testing.T) {
+	bus := &EventBus{}
+
+	var first, second []string
+
+	bus.Subscribe(func(event GameEvent) {
+		first = append(first, event.Event.Type())
+	})
+
+	bus.Subscribe(func(event GameEvent) {
+		// Every handler has seen the event before the next one is published
+		if len(first) != len(second)+1 {
+			t.Errorf("second handler ran before the first for event %d", len(second))
+		}
+
+		second = append(second, event.Event.Type())
+	})
+
+	events := trackedEvents(t, "19.30/member.log")
+
+	for _, event := range events {
+		bus.Publish(event)
+	}
+
+	if want := eventTypes(events); !reflect.DeepEqual(first, want) || !reflect.DeepEqual(second, want) {
+		t.Errorf("got %q and %q, want %q", first, second, want)
+	}
+}
+
+// sentEvent is the part of EventJSON that can be read back, the event itself is an interface
+type sentEvent struct {
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+	To   string    `json:"to"`
+}
+
+func TestEventOutput(t *testing.T) {
+	var mu sync.Mutex
+
+	var received []sentEvent
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event sentEvent
+
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("webhook got invalid JSON: %v", err)
+		}
+
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+	bus := &EventBus{}
+
+	output := &EventOutput{File: file, Webhook: server.URL, Logger: log.New(io.Discard, "", 0)}
+	output.Start(bus)
+
+	events := trackedEvents(t, "19.30/leader.log")
+
+	for _, event := range events {
+		bus.Publish(event)
+	}
+
+	want := eventTypes(events)
+
+	// The output runs in the background, the webhook is sent to after the file is written
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		mu.Lock()
+		done := len(received) >= len(want)
+		mu.Unlock()
+
+		if done {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("webhook got %d of %d events", len(received), len(want))
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	var written []string
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		var event sentEvent
+
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+
+		written = append(written, event.Type)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	sent := make([]string, len(received))
+
+	for i, event := range received {
+		sent[i] = event.Type
+	}
+
+	if !reflect.DeepEqual(written, want) {
+		t.Errorf("file has %q, want %q", written, want)
+	}
+
+	if !reflect.DeepEqual(sent, want) {
+		t.Errorf("webhook got %q, want %q", sent, want)
+	}
+
+	if !received[0].Time.Equal(events[0].Time) || received[0].To != events[0].To.String() {
+		t.Errorf("webhook got %+v for %+v", received[0], events[0])
+	}
+}
+
+func TestPostEventStatus(t *testing.T) {
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	if err := postEvent(server.URL, []byte(`{}`)); err != nil {
+		t.Errorf("200: %v", err)
+	}
+
+	for _, status = range []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError} {
+		if err := postEvent(server.URL, []byte(`{}`)); err == nil {
+			t.Errorf("%d: expected an error", status)
+		}
+	}
+}
+
+func TestEventMetrics(t *testing.T) {
+	metrics := &EventMetrics{}
+
+	events := trackedEvents(t, "19.30/member.log")
+
+	for _, event := range events {
+		metrics.Handle(event)
+	}
+
+	// Replayed events were seen before FNRadio caught up and aren't counted
+	metrics.Handle(GameEvent{PartyTransition: events[0].PartyTransition, Replayed: true})
+
+	counts, matches, _ := metrics.Snapshot()
+
+	want := map[string]int{}
+
+	for _, name := range eventTypes(events) {
+		want[name]++
+	}
+
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("got counts %v, want %v", counts, want)
+	}
+
+	if matches == 0 {
+		t.Error("no matches counted")
+	}
+}
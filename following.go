@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+const followingFile = "following.json"
+
+// Following is the list of users whose stations show up in browse
+type Following struct {
+	mu    sync.Mutex
+	Users []string
+}
+
+var following = &Following{}
+
+func loadFollowing() error {
+	following.mu.Lock()
+	defer following.mu.Unlock()
+
+	return loadConfig(followingFile, &following.Users)
+}
+
+func (f *Following) Add(user string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, existing := range f.Users {
+		if existing == user {
+			return false, nil
+		}
+	}
+
+	f.Users = append(f.Users, user)
+
+	sort.Strings(f.Users)
+
+	return true, saveConfig(followingFile, f.Users)
+}
+
+func (f *Following) Remove(user string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, existing := range f.Users {
+		if existing == user {
+			f.Users = append(f.Users[:i:i], f.Users[i+1:]...)
+
+			return true, saveConfig(followingFile, f.Users)
+		}
+	}
+
+	return false, nil
+}
+
+func (f *Following) List() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string{}, f.Users...)
+}
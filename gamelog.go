@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+const gameLogEnv = "FNRADIO_GAME_LOG"
+
+// wineGameLogPatterns are where Wine, Lutris, Heroic and Proton prefixes usually keep the game's log, relative to the
+// home directory
+var wineGameLogPatterns = []string{
+	".wine/drive_c/users/*/AppData/Local/FortniteGame/Saved/Logs/FortniteGame.log",
+	".wine/drive_c/users/*/Local Settings/Application Data/FortniteGame/Saved/Logs/FortniteGame.log",
+	"Games/*/drive_c/users/*/AppData/Local/FortniteGame/Saved/Logs/FortniteGame.log",
+	"Games/Heroic/Prefixes/*/drive_c/users/*/AppData/Local/FortniteGame/Saved/Logs/FortniteGame.log",
+	".local/share/Steam/steamapps/compatdata/*/pfx/drive_c/users/steamuser/AppData/Local/FortniteGame/Saved/Logs/FortniteGame.log",
+}
+
+// gameLogSource returns where game log lines should be read from, see logreader.Open for the formats other than a
+// file path. FNRADIO_GAME_LOG wins over the game_log setting, which wins over looking for the game's log.
+func gameLogSource() string {
+	if source := os.Getenv(gameLogEnv); source != "" {
+		return source
+	}
+
+	if settings.GameLog != "" {
+		return settings.GameLog
+	}
+
+	local := filepath.Join(os.Getenv("LOCALAPPDATA"), "FortniteGame", "Saved", "Logs", "FortniteGame.log")
+
+	// Under Wine the game is often in a different prefix from ours, such as one Steam or Heroic made for it
+	if _, err := os.Stat(local); err != nil && underWine() {
+		if found := findWineGameLog(); found != "" {
+			return found
+		}
+	}
+
+	return local
+}
+
+// underWine reports whether we are running under Wine or Proton, which export wine_get_version from ntdll
+func underWine() bool {
+	return windows.NewLazySystemDLL("ntdll.dll").NewProc("wine_get_version").Find() == nil
+}
+
+// wineHomeDir returns the Unix home directory as a path we can open, Wine maps the Unix root to Z: by default
+func wineHomeDir() string {
+	if home := os.Getenv("WINEHOMEDIR"); home != "" {
+		return strings.TrimPrefix(home, `\??\`)
+	}
+
+	if home := os.Getenv("HOME"); strings.HasPrefix(home, "/") {
+		return "Z:" + filepath.FromSlash(home)
+	}
+
+	return ""
+}
+
+// findWineGameLog returns the most recently written game log in any known prefix
+func findWineGameLog() string {
+	home := wineHomeDir()
+	if home == "" {
+		return ""
+	}
+
+	var newest string
+
+	var newestModTime int64
+
+	for _, pattern := range wineGameLogPatterns {
+		matches, _ := filepath.Glob(filepath.Join(home, pattern))
+
+		for _, match := range matches {
+			stat, err := os.Stat(match)
+			if err != nil {
+				continue
+			}
+
+			if modTime := stat.ModTime().UnixNano(); newest == "" || modTime > newestModTime {
+				newest = match
+				newestModTime = modTime
+			}
+		}
+	}
+
+	return newest
+}
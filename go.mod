@@ -6,7 +6,10 @@ require (
 	github.com/c-bata/go-prompt v0.2.6
 	github.com/elazarl/goproxy v0.0.0-20220115173737-adb46da277ac
 	github.com/fsnotify/fsnotify v1.5.1
-	golang.org/x/sys v0.0.0-20220209214540-3681064d5158
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	golang.org/x/crypto v0.1.0
+	golang.org/x/sys v0.1.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	credTypeGeneric          = 1
+	credPersistLocalMachine  = 2
+	keyringTargetPrefix      = "FNRadio:"
+	keyringMaxCredentialBlob = 5 * 512
+)
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+// credential is CREDENTIALW from wincred.h
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// keyringCredentials keeps secrets in the Windows Credential Manager, where they are encrypted for the current user
+type keyringCredentials struct{}
+
+// keyringWorks checks the Credential Manager can actually be written to, it can be disabled by policy or missing
+// under Wine
+func keyringWorks() error {
+	store := keyringCredentials{}
+
+	err := store.Save("probe", []byte{1})
+	if err != nil {
+		return err
+	}
+
+	return store.Delete("probe")
+}
+
+// keyringReadable checks secrets can be read from the Credential Manager, a missing secret is fine
+func keyringReadable() bool {
+	_, err := keyringCredentials{}.Load("probe")
+
+	return err == nil || errors.Is(err, ErrCredentialNotFound)
+}
+
+func (keyringCredentials) Load(name string) ([]byte, error) {
+	target, err := syscall.UTF16PtrFromString(keyringTargetPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+
+	var cred *credential
+
+	r, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		if errors.Is(err, windows.ERROR_NOT_FOUND) {
+			return nil, ErrCredentialNotFound
+		}
+
+		return nil, err
+	}
+
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred))) // nolint:errcheck
+
+	return append([]byte{}, unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)...), nil
+}
+
+func (keyringCredentials) Save(name string, value []byte) error {
+	if len(value) == 0 || len(value) > keyringMaxCredentialBlob {
+		return errors.New("secret " + name + " doesn't fit in the credential manager")
+	}
+
+	target, err := syscall.UTF16PtrFromString(keyringTargetPrefix + name)
+	if err != nil {
+		return err
+	}
+
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(value)),
+		CredentialBlob:     &value[0],
+		Persist:            credPersistLocalMachine,
+	}
+
+	r, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if r == 0 {
+		return err
+	}
+
+	return nil
+}
+
+func (keyringCredentials) Delete(name string) error {
+	target, err := syscall.UTF16PtrFromString(keyringTargetPrefix + name)
+	if err != nil {
+		return err
+	}
+
+	r, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if r == 0 && !errors.Is(err, windows.ERROR_NOT_FOUND) {
+		return err
+	}
+
+	return nil
+}
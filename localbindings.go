@@ -0,0 +1,60 @@
+package main
+
+import (
+	"sync"
+)
+
+const localBindingsFile = "bindings.json"
+
+// LocalBindings are bindings only this client knows about, they are never sent to the API
+type LocalBindings struct {
+	mu       sync.Mutex
+	Bindings map[string]APIBinding
+}
+
+var localBindings = &LocalBindings{Bindings: map[string]APIBinding{}}
+
+func loadLocalBindings() error {
+	localBindings.mu.Lock()
+	defer localBindings.mu.Unlock()
+
+	err := loadConfig(localBindingsFile, &localBindings.Bindings)
+
+	// A file containing null leaves us without a map to add to
+	if localBindings.Bindings == nil {
+		localBindings.Bindings = map[string]APIBinding{}
+	}
+
+	return err
+}
+
+func (bindings *LocalBindings) Set(binding APIBinding) error {
+	bindings.mu.Lock()
+	defer bindings.mu.Unlock()
+
+	bindings.Bindings[binding.ID] = binding
+
+	return saveConfig(localBindingsFile, bindings.Bindings)
+}
+
+func (bindings *LocalBindings) Delete(id string) (bool, error) {
+	bindings.mu.Lock()
+	defer bindings.mu.Unlock()
+
+	if _, ok := bindings.Bindings[id]; !ok {
+		return false, nil
+	}
+
+	delete(bindings.Bindings, id)
+
+	return true, saveConfig(localBindingsFile, bindings.Bindings)
+}
+
+func (bindings *LocalBindings) Get(id string) (APIBinding, bool) {
+	bindings.mu.Lock()
+	defer bindings.mu.Unlock()
+
+	binding, ok := bindings.Bindings[id]
+
+	return binding, ok
+}
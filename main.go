@@ -1,7 +1,6 @@
 package main
 
 import (
-	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -11,21 +10,32 @@ import (
 	"os"
 	"os/signal"
 	"regexp"
+	"sync"
 	"syscall"
 
 	"github.com/elazarl/goproxy"
 )
 
 type FNRadioClient struct {
-	Proxy       *goproxy.ProxyHttpServer
-	Certificate *tls.Certificate
-	APIClient   APIClient
-	Users       map[string]APIUser
-	Party       Party
-	BoundUser   string
-	LogFile     io.Writer
-	Logger      *log.Logger
-
+	Proxy        *goproxy.ProxyHttpServer
+	CertCache    *CertCache
+	APIClient    APIClient
+	Users        map[string]APIUser
+	PartyTracker PartyTracker
+	PartyStatus  PartyStatus
+	LogParser    *LogParser
+	Events       *EventBus
+	Metrics      *EventMetrics
+	BoundUser    string
+	LogFile      io.Writer
+	Logger       *log.Logger
+
+	// mu guards the account, users and party state, which the game log, the CLI and the proxy all use. It's never
+	// held across API calls, partyMu keeps party changes in order while they wait on the API instead.
+	mu      sync.Mutex
+	partyMu sync.Mutex
+
+	partySyncPending     bool
 	alreadyProxying      bool
 	previousProxyEnabled uint64
 	previousProxyServer  string
@@ -47,7 +57,7 @@ func setupCloseHandler() {
 func (client *FNRadioClient) handleAkamaizedConnect(host string, _ *goproxy.ProxyCtx) (*goproxy.ConnectAction, string) {
 	return &goproxy.ConnectAction{
 		Action:    goproxy.ConnectMitm,
-		TLSConfig: goproxy.TLSConfigFromCA(client.Certificate),
+		TLSConfig: client.CertCache.TLSConfig,
 	}, host
 }
 
@@ -57,28 +67,65 @@ func (client *FNRadioClient) handleAkamaizedRequest(r *http.Request, _ *goproxy.
 	matchString := regex.FindStringSubmatch(r.URL.Path)
 
 	if len(matchString) > 0 {
-		for _, binding := range client.Users[client.BoundUser].Bindings {
-			if binding.ID == matchString[1] {
-				_ = client.Logger.Output(2, "Rewriting request "+r.URL.String()+" to station "+binding.StationUser+":"+binding.StationID)
+		discovered := discovery.Observe(matchString[1])
+		if discovered && !containsInGameStation(inGameStations, matchString[1]) {
+			_ = client.Logger.Output(2, "Discovered new in-game station "+matchString[1])
+		}
+
+		client.mu.Lock()
+		binding, ok := client.resolveBinding(matchString[1])
+		auth := client.APIClient.generateAuthHeader()
+		party := client.PartyTracker.Party
+		client.mu.Unlock()
 
-				r.URL, _ = url.Parse(APIRoot + "/users/" + binding.StationUser + "/stations/" + binding.StationID)
+		if ok {
+			_ = client.Logger.Output(2, "Rewriting request "+r.URL.String()+" to station "+binding.StationUser+":"+binding.StationID)
 
-				r.Header.Set("Authorization", client.APIClient.generateAuthHeader())
+			r.URL, _ = url.Parse(APIRoot + "/users/" + binding.StationUser + "/stations/" + binding.StationID)
 
-				r.Header.Set("X-API-Root", APIRoot)
+			r.Header.Set("Authorization", auth)
 
-				r.Host = r.URL.Host
+			r.Header.Set("X-API-Root", APIRoot)
 
-				break
+			// Lets the API give everyone in the same match the same start position on static stations
+			if party.Match != "" {
+				r.Header.Set("X-Party-ID", party.ID)
+				r.Header.Set("X-Party-Match", party.Match)
+				r.Header.Set("X-Party-Session", party.Session)
 			}
+
+			r.Host = r.URL.Host
 		}
 	}
 
 	return r, nil
 }
 
+func (client *FNRadioClient) setupEvents() {
+	client.Events = &EventBus{}
+	client.Metrics = &EventMetrics{}
+
+	client.Events.Subscribe(client.syncParty)
+	client.Events.Subscribe(client.Metrics.Handle)
+
+	if settings.EventsFile != "" || settings.EventsWebhook != "" {
+		output := &EventOutput{
+			File:    settings.EventsFile,
+			Webhook: settings.EventsWebhook,
+			Logger:  client.Logger,
+		}
+
+		output.Start(client.Events)
+	}
+}
+
 func (client *FNRadioClient) Destroy() {
 	client.revertSystemProxy()
+
+	err := discovery.Flush()
+	if err != nil {
+		fmt.Println("Failed to save discovered stations: " + err.Error())
+	}
 }
 
 func (client *FNRadioClient) FetchSelf() {
@@ -95,18 +142,149 @@ func (client *FNRadioClient) FetchSelf() {
 	client.Users[client.APIClient.ID] = user
 }
 
+// useAccount switches to the account api has credentials for, saving them once its stations have been fetched
+func (client *FNRadioClient) useAccount(api APIClient) error {
+	user, err := api.GetUser("@me")
+	if err != nil {
+		return err
+	}
+
+	err = api.Save()
+	if err != nil {
+		return err
+	}
+
+	client.mu.Lock()
+	client.APIClient = api
+	client.Users = map[string]APIUser{api.ID: user}
+	client.BoundUser = api.ID
+	party := client.PartyTracker.Party
+	client.mu.Unlock()
+
+	if party.ID != "" {
+		client.handlePartyChange(party)
+	}
+
+	return nil
+}
+
+// currentParty returns the party the game log last put us in
+func (client *FNRadioClient) currentParty() Party {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	return client.PartyTracker.Party
+}
+
+// self returns our own stations and bindings. Only the CLI changes them and it does so through the methods below, so
+// the CLI can read them without holding mu.
+func (client *FNRadioClient) self() APIUser {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	return client.Users[client.APIClient.ID]
+}
+
+// boundUser returns the user whose stations are being used, which is the party leader when following one
+func (client *FNRadioClient) boundUser() APIUser {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	return client.Users[client.BoundUser]
+}
+
+func (client *FNRadioClient) setSelf(user APIUser) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.Users[client.APIClient.ID] = user
+}
+
+func (client *FNRadioClient) setStation(station APIStation) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.Users[client.APIClient.ID].Stations[station.ID] = station
+}
+
+func (client *FNRadioClient) deleteStation(id string) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	delete(client.Users[client.APIClient.ID].Stations, id)
+}
+
+func (client *FNRadioClient) setBinding(binding APIBinding) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.Users[client.APIClient.ID].Bindings[binding.ID] = binding
+}
+
+func (client *FNRadioClient) deleteBinding(id string) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	delete(client.Users[client.APIClient.ID].Bindings, id)
+}
+
 func main() {
+	// Lets account flows such as pairing be tried against a local or fake API
+	if root := os.Getenv("FNRADIO_API_ROOT"); root != "" {
+		APIRoot = root
+	}
+
+	if err := loadCatalogue(); err != nil {
+		fmt.Println("Failed to load in-game stations: " + err.Error())
+	}
+
+	if err := loadSettings(); err != nil {
+		fmt.Println("Failed to load settings: " + err.Error())
+	}
+
+	if err := loadPhaseRules(); err != nil {
+		fmt.Println("Failed to load match phase rules: " + err.Error())
+	}
+
+	if err := loadLocalBindings(); err != nil {
+		fmt.Println("Failed to load local bindings: " + err.Error())
+	}
+
+	if err := loadFollowing(); err != nil {
+		fmt.Println("Failed to load followed users: " + err.Error())
+	}
+
+	if err := loadDefaultProfile(); err != nil {
+		fmt.Println("Failed to load default profile: " + err.Error())
+	}
+
+	if err := loadSchedules(); err != nil {
+		fmt.Println("Failed to load schedules: " + err.Error())
+	}
+
+	if err := setupCredentials(); err != nil {
+		fmt.Println("Failed to move secrets out of the registry: " + err.Error())
+	}
+
+	if len(os.Args) > 1 {
+		os.Exit(runSubcommand(os.Args[1], os.Args[2:]))
+	}
+
 	fmt.Println("FNRadio by Jaren (@The1Jaren) [" + Version + "]")
 	fmt.Println("")
 	fmt.Println("Join our discord: https://discord.gg/bgRM3XdhnA")
 	fmt.Println("SAC Code: Jaren")
 
 	client = &FNRadioClient{
-		Proxy:       goproxy.NewProxyHttpServer(),
-		Certificate: setupSSL(),
-		APIClient:   APIClient{},
-		Users:       map[string]APIUser{},
-		LogFile:     ioutil.Discard,
+		Proxy:     goproxy.NewProxyHttpServer(),
+		CertCache: NewCertCache(),
+		APIClient: APIClient{},
+		Users:     map[string]APIUser{},
+		LogFile:   ioutil.Discard,
+	}
+
+	if err := client.CertCache.SetCA(setupSSL()); err != nil {
+		panic(err)
 	}
 
 	logFile, err := os.OpenFile("FNRadio.log", os.O_CREATE|os.O_TRUNC, 0666)
@@ -116,12 +294,18 @@ func main() {
 
 	client.Logger = log.New(client.LogFile, "[FNRadio] ", log.LstdFlags)
 
+	client.LogParser = newConfiguredLogParser("", client.Logger)
+
+	client.setupEvents()
+
 	client.APIClient.Setup()
 
 	client.FetchSelf()
 
 	client.Proxy.Verbose = true
 
+	client.Proxy.CertStore = client.CertCache
+
 	client.Proxy.Logger = log.New(client.LogFile, "[GoProxy] ", log.LstdFlags)
 
 	client.Proxy.OnRequest(goproxy.ReqHostIs("fortnite-vod.akamaized.net:443")).HandleConnect(goproxy.FuncHttpsHandler(client.handleAkamaizedConnect))
@@ -138,6 +322,8 @@ func main() {
 
 	go client.readGameLog()
 
+	go client.runScheduler()
+
 	err = http.ListenAndServe("127.0.0.1:18149", client.Proxy)
 	if err != nil {
 		fmt.Println(err)
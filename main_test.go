@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAkamaizedRequestPartyHeaders(t *testing.T) {
+	testClient(t, fakePartyAPI{}, map[string]APIStation{"lofi": {ID: "lofi", Type: StationTypeStream}})
+
+	id := inGameStations[0].ID
+
+	client.setBinding(APIBinding{ID: id, StationUser: replaySelfID, StationID: "lofi"})
+
+	tests := []struct {
+		name    string
+		party   Party
+		headers bool
+	}{
+		{"no party", Party{}, false},
+		{"party in the lobby", Party{ID: "party-a"}, false},
+		{"party in a match", Party{ID: "party-a", Match: "match-a", Session: "session-a"}, true},
+	}
+
+	for _, test := range tests {
+		client.mu.Lock()
+		client.PartyTracker.Party = test.party
+		client.mu.Unlock()
+
+		r, _ := client.handleAkamaizedRequest(httptest.NewRequest(http.MethodGet, "https://fortnite-vod.akamaized.net/"+id+"/master.blurl", nil), nil)
+
+		if want := APIRoot + "/users/" + replaySelfID + "/stations/lofi"; r.URL.String() != want {
+			t.Errorf("%s: request went to %s, want %s", test.name, r.URL, want)
+		}
+
+		want := map[string]string{}
+
+		if test.headers {
+			want = map[string]string{"X-Party-ID": "party-a", "X-Party-Match": "match-a", "X-Party-Session": "session-a"}
+		}
+
+		for _, header := range []string{"X-Party-ID", "X-Party-Match", "X-Party-Session"} {
+			if got := r.Header.Get(header); got != want[header] {
+				t.Errorf("%s: %s is %q, want %q", test.name, header, got, want[header])
+			}
+		}
+	}
+
+	// Requests that pass through to Epic don't get any of our headers
+	client.deleteBinding(id)
+
+	r, _ := client.handleAkamaizedRequest(httptest.NewRequest(http.MethodGet, "https://fortnite-vod.akamaized.net/"+id+"/master.blurl", nil), nil)
+
+	if r.URL.Host != "fortnite-vod.akamaized.net" || r.Header.Get("X-Party-ID") != "" || r.Header.Get("Authorization") != "" {
+		t.Errorf("passthrough request was changed: %s %v", r.URL, r.Header)
+	}
+}
@@ -1,22 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
 	"jaren.wtf/fnradio/client/pkg/logreader"
 )
 
-var onPartyCreate = regexp.MustCompile(`LogOnlineParty: MCP: OnCreatePartyComplete: User=\[([0-9a-f]{32})] Party=\[(V2:[0-9a-f]{32})]`)
-var onPartyJoin = regexp.MustCompile(`LogOnlineParty: MCP: JoinParty: User=\[([0-9a-f]{32})] .+PartyId\((V2:[0-9a-f]{32})\)`)
-var onPartyNewLeader = regexp.MustCompile(`LogOnlineParty: MCP: OnPartyNewLeader: User=\[([0-9a-f]{32})] Party=\[(V2:[0-9a-f]{32})] NewLeader=\[([0-9a-f]{32}|[0-9a-f]{5}\.\.\.[0-9a-f]{5})]`)
-var onPartyMemberPromoted = regexp.MustCompile(`LogParty: Verbose: Member \[MCP:([0-9a-f]{32}|[0-9a-f]{5}\.\.\.[0-9a-f]{5}), Party \((V2:[0-9a-f]{32})\)] promoted to party leader`)
-var onMatchmakingSession = regexp.MustCompile(`LogMatchmakingServiceClient: Verbose: HandleWebSocketMessage - Received message: "{"payload":{"matchId":"([0-9a-f]{32})","sessionId":"([0-9a-f]{32})","joinDelaySec":\d+},"name":"Play"}"`)
-
 type Party struct {
 	ID      string `json:"id"`
 	Match   string `json:"match"`
@@ -28,126 +20,219 @@ func (party *Party) Equals(party2 Party) bool {
 	return party.ID == party2.ID && party.Leader == party2.Leader && party.Match == party2.Match && party.Session == party2.Session
 }
 
-func (client *FNRadioClient) handlePartyChange(oldParty Party, newParty Party) {
-	if oldParty.Match != newParty.Match {
-		for i := 1; i < 5; i++ {
-			leader, err := client.APIClient.SetParty(newParty)
-			if err == nil {
-				if leader != "" {
-					_ = client.Logger.Output(2, "Successfully set FNRadio party with leader "+leader)
-				} else {
-					_ = client.Logger.Output(2, "Successfully disabled FNRadio party")
-				}
-
-				if leader != client.APIClient.ID {
-					_ = client.Logger.Output(2, "Fetching party leader "+leader)
-
-					client.Users[leader], err = client.APIClient.GetUser(leader)
-					if err != nil {
-						_ = client.Logger.Output(2, "Error fetching party leader: "+err.Error())
-					} else {
-						client.BoundUser = leader
-					}
-				} else if client.BoundUser != client.APIClient.ID {
-					delete(client.Users, client.BoundUser)
-					client.BoundUser = client.APIClient.ID
-				}
-
-				break
-			}
+// PartyStatus is what the API last told us about the party, and whose stations we ended up using because of it
+type PartyStatus struct {
+	Leader  string
+	Members []PartyMember
+	Reason  string
+}
 
-			_ = client.Logger.Output(2, "Failed to set party: "+err.Error())
+func (client *FNRadioClient) bindSelf(reason string) {
+	if client.BoundUser != client.APIClient.ID {
+		delete(client.Users, client.BoundUser)
+		client.BoundUser = client.APIClient.ID
+	}
 
-			time.Sleep(time.Second)
-		}
+	client.PartyStatus.Reason = reason
+}
+
+// partyBinding is whose stations to use after a party change, with the leader's stations when they aren't ours
+type partyBinding struct {
+	User   string
+	Leader APIUser
+	Reason string
+}
+
+func (client *FNRadioClient) followPartyBinding(binding partyBinding) {
+	if binding.User == client.APIClient.ID {
+		client.bindSelf(binding.Reason)
+		return
 	}
+
+	client.Users[binding.User] = binding.Leader
+	client.BoundUser = binding.User
+	client.PartyStatus.Reason = binding.Reason
 }
 
-func (client *FNRadioClient) handleGameLogLines(lines []string) { // nolint:funlen
-	old := client.Party
+// choosePartyBinding works out whose stations to use from what the API said about the party, fetching the leader's
+// stations when we follow them
+func (client *FNRadioClient) choosePartyBinding(api APIClient, party Party, response PartyResponse) partyBinding {
+	self := func(reason string) partyBinding {
+		return partyBinding{User: api.ID, Reason: reason}
+	}
 
-	for _, line := range lines {
-		if strings.Contains(line, "LogOnlineGame: FortPC::ReturnToMainMenu()") {
-			client.Party.Match = ""
-			client.Party.Session = ""
+	switch {
+	case party.ID == "":
+		return self("You aren't in a party")
+	case response.Leader == "" && party.Match == "":
+		return self("Party stations are only shared during a match")
+	case response.Leader == "":
+		return self("The party leader isn't running FNRadio")
+	case response.Leader == api.ID:
+		return self("You are the party leader, the party hears your stations")
+	case settings.PartyMode == PartyModeSelf:
+		return self("Party mode is set to always use your own stations")
+	}
 
-			_ = client.Logger.Output(2, "Reset match info as we have returned to main menu")
+	_ = client.Logger.Output(2, "Fetching party leader "+response.Leader)
 
-			continue
-		}
+	user, err := api.GetUser(response.Leader)
+	if err != nil {
+		_ = client.Logger.Output(2, "Error fetching party leader: "+err.Error())
 
-		onCreate := onPartyCreate.FindStringSubmatch(line)
-		if len(onCreate) != 0 {
-			client.Party.ID = onCreate[2]
-			client.Party.Leader = true
+		return self("The party leader's stations couldn't be fetched")
+	}
 
-			_ = client.Logger.Output(2, "Created party "+client.Party.ID)
+	return partyBinding{User: response.Leader, Leader: user, Reason: "Following the party leader"}
+}
 
-			continue
-		}
+func (client *FNRadioClient) setParty(api APIClient, party Party) (PartyResponse, error) {
+	var err error
 
-		onJoin := onPartyJoin.FindStringSubmatch(line)
-		if len(onJoin) != 0 {
-			client.Party.ID = onJoin[2]
-			client.Party.Leader = false
+	for i := 1; i < 5; i++ {
+		var response PartyResponse
 
-			_ = client.Logger.Output(2, "Joined party "+client.Party.ID)
+		response, err = api.SetParty(party)
+		if err == nil {
+			if response.Leader != "" {
+				_ = client.Logger.Output(2, "Successfully set FNRadio party with leader "+response.Leader)
+			} else {
+				_ = client.Logger.Output(2, "Successfully disabled FNRadio party")
+			}
 
-			continue
+			return response, nil
 		}
 
-		onNewLeader := onPartyNewLeader.FindStringSubmatch(line)
-		if len(onNewLeader) != 0 {
-			client.Party.ID = onNewLeader[2]
-			client.Party.Leader = !strings.Contains(onNewLeader[3], "...")
+		_ = client.Logger.Output(2, "Failed to set party: "+err.Error())
 
-			_ = client.Logger.Output(2, "Updated party leader status to "+strconv.FormatBool(client.Party.Leader))
+		time.Sleep(time.Second)
+	}
 
-			continue
-		}
+	return PartyResponse{}, err
+}
+
+// handlePartyChange tells the API about the party and switches to the stations it leads to. Party changes run one at
+// a time, but mu is only held to read the account and apply the result so the proxy isn't kept waiting on the API.
+func (client *FNRadioClient) handlePartyChange(newParty Party) {
+	client.partyMu.Lock()
+	defer client.partyMu.Unlock()
+
+	client.mu.Lock()
+	api := client.APIClient
+	client.mu.Unlock()
+
+	binding := partyBinding{User: api.ID, Reason: "The FNRadio API couldn't be reached to set up the party"}
+
+	response, err := client.setParty(api, newParty)
+	if err == nil {
+		binding = client.choosePartyBinding(api, newParty, response)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
 
-		onMemberPromoted := onPartyMemberPromoted.FindStringSubmatch(line)
-		if len(onMemberPromoted) != 0 {
-			client.Party.ID = onMemberPromoted[2]
-			client.Party.Leader = !strings.Contains(onMemberPromoted[1], "...")
+	// useAccount sets the party up again for the account it switched to
+	if client.APIClient.ID != api.ID {
+		return
+	}
 
-			_ = client.Logger.Output(2, "Updated party leader status to "+strconv.FormatBool(client.Party.Leader))
+	if err == nil {
+		client.PartyStatus.Leader = response.Leader
+		client.PartyStatus.Members = response.Members
+	}
 
-			continue
+	client.followPartyBinding(binding)
+}
+
+// publishGameLogLines runs lines through the party tracker and publishes every transition on the event bus
+func (client *FNRadioClient) publishGameLogLines(lines []string, replayed bool) {
+	for _, line := range lines {
+		event, ok := client.LogParser.Parse(line)
+		if ok {
+			client.publishPartyEvent(event, replayed)
 		}
+	}
+}
+
+func (client *FNRadioClient) publishPartyEvent(event PartyEvent, replayed bool) {
+	client.mu.Lock()
+	transition := client.PartyTracker.Apply(event)
+	client.mu.Unlock()
 
-		onSession := onMatchmakingSession.FindStringSubmatch(line)
-		if len(onSession) != 0 {
-			client.Party.Match = onSession[1]
-			client.Party.Session = onSession[2]
+	_ = client.Logger.Output(2, transition.String())
 
-			_ = client.Logger.Output(2, "Joined match "+client.Party.Match+"/"+client.Party.Session)
+	client.Events.Publish(GameEvent{
+		PartyTransition: transition,
+		Time:            time.Now(),
+		Replayed:        replayed,
+	})
+}
+
+// syncParty is the event bus subscriber that tells the API about party changes. Changes read while catching up with
+// the game log are held back until we have caught up, as only the state they leave us in matters.
+func (client *FNRadioClient) syncParty(event GameEvent) {
+	setParty := false
 
-			continue
+	for _, action := range event.Actions {
+		if action == PartyActionSetParty {
+			setParty = true
 		}
 	}
 
-	if !old.Equals(client.Party) {
-		client.handlePartyChange(old, client.Party)
+	client.mu.Lock()
+
+	if event.Replayed {
+		client.partySyncPending = client.partySyncPending || setParty
+		client.mu.Unlock()
+
+		return
+	}
+
+	setParty = setParty || client.partySyncPending
+	client.partySyncPending = false
+
+	client.mu.Unlock()
+
+	if setParty {
+		client.handlePartyChange(event.New)
 	}
 }
 
+func (client *FNRadioClient) handleGameLogLines(lines []string) {
+	client.publishGameLogLines(lines, false)
+}
+
+func isLogFileOpen(line string) bool {
+	return strings.HasPrefix(line, "Log file open")
+}
+
 func (client *FNRadioClient) readGameLog() {
-	reader, err := logreader.New(os.Getenv("LOCALAPPDATA") + `\FortniteGame\Saved\Logs\FortniteGame.log`)
+	spec := gameLogSource()
+	if spec == "" {
+		fmt.Println("Couldn't find FortniteGame.log, set " + gameLogEnv + " to its path to enable party sync")
+		return
+	}
+
+	source, err := logreader.Open(context.Background(), spec, logreader.FromLastMatch(isLogFileOpen))
 	if err != nil {
-		panic(err)
+		fmt.Println("Failed to read game log " + spec + ": " + err.Error())
+		return
 	}
 
-	for event := range reader.Events {
+	caughtUp := false
+
+	for event := range source.Lines() {
 		if event.Error != nil {
 			fmt.Println(event.Error)
 			break
 		}
 
-		if event.Initial && strings.Contains(event.Lines[len(event.Lines)-1], "Log file closed, ") {
-			continue // The game isn't open right now, so we really don't care what the logs say is happening
+		if !event.Initial && !caughtUp {
+			caughtUp = true
+
+			client.publishPartyEvent(CaughtUpEvent{}, false)
 		}
 
-		client.handleGameLogLines(event.Lines)
+		client.publishGameLogLines(event.Lines, event.Initial)
 	}
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testClient points the global client at api, as the replay user with the given stations
+func testClient(t *testing.T, api http.Handler, stations map[string]APIStation) {
+	t.Helper()
+
+	tempConfigDir(t)
+
+	server := httptest.NewServer(api)
+
+	oldRoot, oldClient, oldMode, oldDiscovery := APIRoot, client, settings.PartyMode, discovery
+
+	t.Cleanup(func() {
+		_ = discovery.Flush()
+
+		APIRoot, client, settings.PartyMode, discovery = oldRoot, oldClient, oldMode, oldDiscovery
+
+		server.Close()
+	})
+
+	APIRoot = server.URL
+	discovery = &Discovery{Stations: map[string]*DiscoveredStation{}}
+
+	client = &FNRadioClient{
+		APIClient: APIClient{ID: replaySelfID, Secret: "replay"},
+		Users:     map[string]APIUser{replaySelfID: {Stations: stations, Bindings: map[string]APIBinding{}}},
+		BoundUser: replaySelfID,
+		Logger:    log.New(io.Discard, "", 0),
+		Events:    &EventBus{},
+	}
+
+	client.Events.Subscribe(client.syncParty)
+}
+
+// followGameLog publishes the events of a log fixture a few times over in the background
+func followGameLog(t *testing.T, file string, wg *sync.WaitGroup) {
+	t.Helper()
+
+	events := fixtureEvents(t, file)
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 5; i++ {
+			for _, event := range events {
+				client.publishPartyEvent(event, false)
+			}
+		}
+	}()
+}
+
+// TestPartyCommandDuringSync changes the party mode while the game log is being followed, run with -race
+func TestPartyCommandDuringSync(t *testing.T) {
+	testClient(t, fakePartyAPI{}, map[string]APIStation{})
+
+	var wg sync.WaitGroup
+
+	followGameLog(t, "19.30/member.log", &wg)
+
+	cli := &CLI{}
+
+	for i := 0; i < 20; i++ {
+		if i%2 == 0 {
+			cli.partyCmd([]string{PartyModeSelf})
+		} else {
+			cli.partyCmd([]string{PartyModeFollow})
+		}
+	}
+
+	wg.Wait()
+}
+
+// TestProxyDuringSync serves requests and changes bindings while the game log is being followed, run with -race
+func TestProxyDuringSync(t *testing.T) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/users/@me/bindings/") {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		fakePartyAPI{}.ServeHTTP(w, r)
+	})
+
+	testClient(t, api, map[string]APIStation{"lofi": {ID: "lofi", Type: StationTypeStream}})
+
+	inGameStation := inGameStations[0]
+
+	var wg sync.WaitGroup
+
+	followGameLog(t, "19.30/member.log", &wg)
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 200; i++ {
+			r := httptest.NewRequest(http.MethodGet, "https://fortnite-vod.akamaized.net/"+inGameStation.ID+"/master.blurl", nil)
+
+			client.handleAkamaizedRequest(r, nil)
+		}
+	}()
+
+	cli := &CLI{}
+
+	for i := 0; i < 20; i++ {
+		cli.bindCmd([]string{"lofi", inGameStation.Name})
+		cli.unbindCmd([]string{inGameStation.Name})
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"strconv"
+)
+
+type PartyState int
+
+const (
+	PartyStateNone PartyState = iota
+	PartyStateLobbyLeader
+	PartyStateLobbyMember
+	PartyStateMatchmaking
+	PartyStateInMatch
+	PartyStateReturnedToMenu
+)
+
+func (state PartyState) String() string {
+	switch state {
+	case PartyStateNone:
+		return "no party"
+	case PartyStateLobbyLeader:
+		return "in lobby as leader"
+	case PartyStateLobbyMember:
+		return "in lobby as member"
+	case PartyStateMatchmaking:
+		return "matchmaking"
+	case PartyStateInMatch:
+		return "in match"
+	case PartyStateReturnedToMenu:
+		return "returned to menu"
+	}
+
+	return "unknown (" + strconv.Itoa(int(state)) + ")"
+}
+
+type MatchPhase string
+
+const (
+	MatchPhaseLobby   MatchPhase = "lobby"
+	MatchPhaseWarmup  MatchPhase = "warmup"
+	MatchPhaseBus     MatchPhase = "bus"
+	MatchPhaseMatch   MatchPhase = "match"
+	MatchPhaseEndgame MatchPhase = "endgame"
+	MatchPhaseVictory MatchPhase = "victory"
+)
+
+var matchPhases = []MatchPhase{MatchPhaseLobby, MatchPhaseWarmup, MatchPhaseBus, MatchPhaseMatch, MatchPhaseEndgame, MatchPhaseVictory}
+
+// gamePhases maps the game's EAthenaGamePhase names to our match phases
+var gamePhases = map[string]MatchPhase{
+	"Setup":     MatchPhaseWarmup,
+	"Warmup":    MatchPhaseWarmup,
+	"Aircraft":  MatchPhaseBus,
+	"SafeZones": MatchPhaseMatch,
+	"EndGame":   MatchPhaseEndgame,
+}
+
+type PartyEvent interface {
+	Type() string
+	String() string
+}
+
+type PartyCreatedEvent struct {
+	PartyID string `json:"party_id"`
+}
+
+func (event PartyCreatedEvent) Type() string {
+	return "party_created"
+}
+
+func (event PartyCreatedEvent) String() string {
+	return "Created party " + event.PartyID
+}
+
+type PartyJoinedEvent struct {
+	PartyID string `json:"party_id"`
+}
+
+func (event PartyJoinedEvent) Type() string {
+	return "party_joined"
+}
+
+func (event PartyJoinedEvent) String() string {
+	return "Joined party " + event.PartyID
+}
+
+type PartyLeaderChangedEvent struct {
+	PartyID string `json:"party_id"`
+	Leader  bool   `json:"leader"`
+}
+
+func (event PartyLeaderChangedEvent) Type() string {
+	return "party_leader_changed"
+}
+
+func (event PartyLeaderChangedEvent) String() string {
+	return "Updated party leader status to " + strconv.FormatBool(event.Leader)
+}
+
+type MatchmakingStartedEvent struct {
+	Status string `json:"status"`
+}
+
+func (event MatchmakingStartedEvent) Type() string {
+	return "matchmaking_status"
+}
+
+func (event MatchmakingStartedEvent) String() string {
+	return "Matchmaking status " + event.Status
+}
+
+type MatchJoinedEvent struct {
+	Match   string `json:"match"`
+	Session string `json:"session"`
+}
+
+func (event MatchJoinedEvent) Type() string {
+	return "match_joined"
+}
+
+func (event MatchJoinedEvent) String() string {
+	return "Joined match " + event.Match + "/" + event.Session
+}
+
+type GamePhaseEvent struct {
+	Phase string `json:"phase"`
+}
+
+func (event GamePhaseEvent) Type() string {
+	return "game_phase"
+}
+
+func (event GamePhaseEvent) String() string {
+	return "Game phase changed to " + event.Phase
+}
+
+type MatchWonEvent struct{}
+
+func (event MatchWonEvent) Type() string {
+	return "match_won"
+}
+
+func (event MatchWonEvent) String() string {
+	return "Won the match"
+}
+
+type ReturnedToMenuEvent struct{}
+
+func (event ReturnedToMenuEvent) Type() string {
+	return "returned_to_menu"
+}
+
+func (event ReturnedToMenuEvent) String() string {
+	return "Returned to main menu"
+}
+
+type GameClosedEvent struct{}
+
+func (event GameClosedEvent) Type() string {
+	return "game_closed"
+}
+
+func (event GameClosedEvent) String() string {
+	return "Game closed"
+}
+
+// CaughtUpEvent is sent once everything already in the game log has been read
+type CaughtUpEvent struct{}
+
+func (event CaughtUpEvent) Type() string {
+	return "caught_up"
+}
+
+func (event CaughtUpEvent) String() string {
+	return "Caught up with the game log"
+}
+
+type PartyAction int
+
+const (
+	// PartyActionSetParty sends the party to the API, which answers with the leader whose stations should be used
+	PartyActionSetParty PartyAction = iota
+)
+
+type PartyTransition struct {
+	Event     PartyEvent
+	From      PartyState
+	To        PartyState
+	FromPhase MatchPhase
+	ToPhase   MatchPhase
+	Old       Party
+	New       Party
+	Actions   []PartyAction
+}
+
+func (transition PartyTransition) String() string {
+	message := transition.Event.String()
+
+	if transition.From != transition.To {
+		message += " (" + transition.From.String() + " -> " + transition.To.String() + ")"
+	}
+
+	if transition.FromPhase != transition.ToPhase {
+		message += " (phase " + string(transition.FromPhase) + " -> " + string(transition.ToPhase) + ")"
+	}
+
+	return message
+}
+
+// PartyTracker follows the player's party and match from game events
+type PartyTracker struct {
+	State PartyState
+	Phase MatchPhase
+	Party Party
+}
+
+// CurrentPhase is the match phase, which is the lobby until the game tells us otherwise
+func (tracker *PartyTracker) CurrentPhase() MatchPhase {
+	if tracker.Phase == "" {
+		return MatchPhaseLobby
+	}
+
+	return tracker.Phase
+}
+
+func (tracker *PartyTracker) lobbyState() PartyState {
+	switch {
+	case tracker.Party.Match != "":
+		return PartyStateInMatch
+	case tracker.Party.ID == "":
+		return PartyStateNone
+	case tracker.State == PartyStateMatchmaking:
+		return PartyStateMatchmaking
+	case tracker.Party.Leader:
+		return PartyStateLobbyLeader
+	default:
+		return PartyStateLobbyMember
+	}
+}
+
+func (tracker *PartyTracker) Apply(event PartyEvent) PartyTransition {
+	transition := PartyTransition{
+		Event:     event,
+		From:      tracker.State,
+		FromPhase: tracker.CurrentPhase(),
+		Old:       tracker.Party,
+	}
+
+	switch e := event.(type) {
+	case PartyCreatedEvent:
+		tracker.Party.ID = e.PartyID
+		tracker.Party.Leader = true
+		tracker.State = tracker.lobbyState()
+	case PartyJoinedEvent:
+		tracker.Party.ID = e.PartyID
+		tracker.Party.Leader = false
+		tracker.State = tracker.lobbyState()
+	case PartyLeaderChangedEvent:
+		tracker.Party.ID = e.PartyID
+		tracker.Party.Leader = e.Leader
+		tracker.State = tracker.lobbyState()
+	case MatchmakingStartedEvent:
+		if tracker.State != PartyStateInMatch {
+			tracker.State = PartyStateMatchmaking
+		}
+	case MatchJoinedEvent:
+		tracker.Party.Match = e.Match
+		tracker.Party.Session = e.Session
+		tracker.State = PartyStateInMatch
+		tracker.Phase = MatchPhaseWarmup
+	case GamePhaseEvent:
+		if phase, ok := gamePhases[e.Phase]; ok && tracker.Phase != MatchPhaseVictory {
+			tracker.Phase = phase
+		}
+	case MatchWonEvent:
+		tracker.Phase = MatchPhaseVictory
+	case ReturnedToMenuEvent:
+		tracker.Party.Match = ""
+		tracker.Party.Session = ""
+		tracker.State = PartyStateReturnedToMenu
+		tracker.Phase = MatchPhaseLobby
+	case GameClosedEvent:
+		tracker.Party = Party{}
+		tracker.State = PartyStateNone
+		tracker.Phase = MatchPhaseLobby
+	}
+
+	transition.To = tracker.State
+	transition.ToPhase = tracker.CurrentPhase()
+	transition.New = tracker.Party
+
+	if !transition.Old.Equals(transition.New) {
+		transition.Actions = append(transition.Actions, PartyActionSetParty)
+	}
+
+	return transition
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureEvents runs a testdata log through the bundled log patterns, returning the events it produces
+func fixtureEvents(t *testing.T, file string) []PartyEvent {
+	t.Helper()
+
+	sets, err := loadPatternSets("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join("testdata", "logs", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewLogParser(sets, log.New(io.Discard, "", 0))
+
+	var events []PartyEvent
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if event, ok := parser.Parse(strings.TrimSuffix(line, "\r")); ok {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+type trackerStep struct {
+	Event    string
+	State    PartyState
+	SetParty bool
+}
+
+func TestPartyTrackerFixtures(t *testing.T) {
+	tests := []struct {
+		file  string
+		steps []trackerStep
+	}{
+		{"19.30/leader.log", []trackerStep{
+			{"party_created", PartyStateLobbyLeader, true},
+			{"matchmaking_status", PartyStateMatchmaking, false},
+			{"matchmaking_status", PartyStateMatchmaking, false},
+			{"matchmaking_status", PartyStateMatchmaking, false},
+			{"matchmaking_status", PartyStateMatchmaking, false},
+			{"match_joined", PartyStateInMatch, true},
+			{"returned_to_menu", PartyStateReturnedToMenu, true},
+			{"matchmaking_status", PartyStateMatchmaking, false},
+			{"match_joined", PartyStateInMatch, true},
+			{"returned_to_menu", PartyStateReturnedToMenu, true},
+			{"game_closed", PartyStateNone, true},
+		}},
+		{"19.30/member.log", []trackerStep{
+			{"party_created", PartyStateLobbyLeader, true},
+			{"party_joined", PartyStateLobbyMember, true},
+			{"match_joined", PartyStateInMatch, true},
+			{"returned_to_menu", PartyStateReturnedToMenu, true},
+			{"party_leader_changed", PartyStateLobbyLeader, true},
+			{"match_joined", PartyStateInMatch, true},
+			{"game_closed", PartyStateNone, true},
+		}},
+		{"19.10/promoted.log", []trackerStep{
+			{"party_joined", PartyStateLobbyMember, true},
+			{"party_leader_changed", PartyStateLobbyMember, false},
+			{"party_leader_changed", PartyStateLobbyLeader, true},
+			{"match_joined", PartyStateInMatch, true},
+			{"returned_to_menu", PartyStateReturnedToMenu, true},
+		}},
+		// Leaving a party puts us in a new party of our own
+		{"19.30/leave.log", []trackerStep{
+			{"party_created", PartyStateLobbyLeader, true},
+			{"party_joined", PartyStateLobbyMember, true},
+			{"party_created", PartyStateLobbyLeader, true},
+			{"game_closed", PartyStateNone, true},
+		}},
+		// Being kicked looks the same as leaving, the game makes us a new party
+		{"19.30/kicked.log", []trackerStep{
+			{"party_joined", PartyStateLobbyMember, true},
+			{"match_joined", PartyStateInMatch, true},
+			{"returned_to_menu", PartyStateReturnedToMenu, true},
+			{"party_created", PartyStateLobbyLeader, true},
+			{"game_closed", PartyStateNone, true},
+		}},
+		// The game crashes mid-match, then reconnects to the same match after it's restarted
+		{"19.30/reconnect.log", []trackerStep{
+			{"party_created", PartyStateLobbyLeader, true},
+			{"match_joined", PartyStateInMatch, true},
+			{"game_closed", PartyStateNone, true},
+			{"party_created", PartyStateLobbyLeader, true},
+			{"match_joined", PartyStateInMatch, true},
+			{"returned_to_menu", PartyStateReturnedToMenu, true},
+			{"game_closed", PartyStateNone, true},
+		}},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.file, func(t *testing.T) {
+			var tracker PartyTracker
+
+			events := fixtureEvents(t, test.file)
+			if len(events) != len(test.steps) {
+				t.Fatalf("got %d events, want %d: %v", len(events), len(test.steps), events)
+			}
+
+			for i, event := range events {
+				transition := tracker.Apply(event)
+
+				got := trackerStep{
+					Event:    event.Type(),
+					State:    transition.To,
+					SetParty: len(transition.Actions) == 1 && transition.Actions[0] == PartyActionSetParty,
+				}
+
+				if got != test.steps[i] {
+					t.Errorf("step %d (%s): got %+v, want %+v", i, transition, got, test.steps[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPartyTrackerGameClosedClearsParty(t *testing.T) {
+	tracker := PartyTracker{}
+
+	tracker.Apply(PartyJoinedEvent{PartyID: "party"})
+	tracker.Apply(MatchJoinedEvent{Match: "match", Session: "session"})
+	tracker.Apply(GamePhaseEvent{Phase: "Aircraft"})
+
+	transition := tracker.Apply(GameClosedEvent{})
+
+	if transition.New != (Party{}) || transition.To != PartyStateNone || transition.ToPhase != MatchPhaseLobby {
+		t.Errorf("party not cleared: %+v", transition)
+	}
+
+	if len(transition.Actions) != 1 || transition.Actions[0] != PartyActionSetParty {
+		t.Errorf("got actions %v, want SetParty", transition.Actions)
+	}
+
+	if transition := tracker.Apply(GameClosedEvent{}); len(transition.Actions) != 0 {
+		t.Errorf("closing again should do nothing, got actions %v", transition.Actions)
+	}
+}
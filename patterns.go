@@ -0,0 +1,333 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	patternsFile = "patterns.json"
+
+	// patternWarningLines is how many lines of a session can pass without any pattern matching before we warn that
+	// the patterns are probably out of date
+	patternWarningLines = 50000
+)
+
+const (
+	PatternReturnedToMenu     = "returned_to_menu"
+	PatternPartyCreated       = "party_created"
+	PatternPartyJoined        = "party_joined"
+	PatternPartyLeaderChanged = "party_leader_changed"
+	PatternMatchmakingStatus  = "matchmaking_status"
+	PatternMatchJoined        = "match_joined"
+	PatternGamePhase          = "game_phase"
+	PatternMatchWon           = "match_won"
+)
+
+var onLogFileOpen = regexp.MustCompile(`^Log file open`)
+var onLogFileClosed = regexp.MustCompile(`Log file closed`)
+var onGameVersion = regexp.MustCompile(`LogInit: Build: \+\+Fortnite\+Release-(\d+\.\d+)`)
+
+//go:embed patterns.json
+var bundledPatterns []byte
+
+type LogPattern struct {
+	Event string `json:"event"`
+	Regex string `json:"regex"`
+
+	compiled *regexp.Regexp
+}
+
+type PatternSet struct {
+	Name       string       `json:"name"`
+	MinVersion string       `json:"min_version,omitempty"`
+	MaxVersion string       `json:"max_version,omitempty"`
+	Patterns   []LogPattern `json:"patterns"`
+}
+
+type PatternFile struct {
+	Version int          `json:"version"`
+	Sets    []PatternSet `json:"sets"`
+}
+
+type GameVersion struct {
+	Major int
+	Minor int
+}
+
+func parseGameVersion(version string) (GameVersion, error) {
+	split := strings.SplitN(version, ".", 2)
+
+	major, err := strconv.Atoi(split[0])
+	if err != nil {
+		return GameVersion{}, err
+	}
+
+	minor := 0
+
+	if len(split) == 2 {
+		minor, err = strconv.Atoi(split[1])
+		if err != nil {
+			return GameVersion{}, err
+		}
+	}
+
+	return GameVersion{Major: major, Minor: minor}, nil
+}
+
+func (v GameVersion) Less(v2 GameVersion) bool {
+	return v.Major < v2.Major || (v.Major == v2.Major && v.Minor < v2.Minor)
+}
+
+func (set *PatternSet) compile() error {
+	if set.Name == "" {
+		return errors.New("pattern set without a name")
+	}
+
+	for _, version := range []string{set.MinVersion, set.MaxVersion} {
+		if version != "" {
+			if _, err := parseGameVersion(version); err != nil {
+				return fmt.Errorf("pattern set %s: invalid version %q", set.Name, version)
+			}
+		}
+	}
+
+	for i, pattern := range set.Patterns {
+		compiled, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return fmt.Errorf("pattern set %s: %s: %w", set.Name, pattern.Event, err)
+		}
+
+		set.Patterns[i].compiled = compiled
+	}
+
+	return nil
+}
+
+func (set *PatternSet) Supports(version GameVersion) bool {
+	if set.MinVersion != "" {
+		minVersion, _ := parseGameVersion(set.MinVersion)
+		if version.Less(minVersion) {
+			return false
+		}
+	}
+
+	if set.MaxVersion != "" {
+		maxVersion, _ := parseGameVersion(set.MaxVersion)
+		if maxVersion.Less(version) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parsePatternFile(data []byte) ([]PatternSet, error) {
+	var file PatternFile
+
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range file.Sets {
+		err = file.Sets[i].compile()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file.Sets, nil
+}
+
+// loadPatternSets returns the pattern sets from file followed by the bundled ones, so sets from file win when both
+// support a game version
+func loadPatternSets(file string) ([]PatternSet, error) {
+	sets, err := parsePatternFile(bundledPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	if file == "" {
+		return sets, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return sets, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	local, err := parsePatternFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(local, sets...), nil
+}
+
+// LogParser turns game log lines into party events using the pattern set for the running game version
+type LogParser struct {
+	Sets    []PatternSet
+	Set     *PatternSet
+	Version string
+	Logger  *log.Logger
+
+	sessionLines   int
+	sessionMatches int
+	warned         bool
+}
+
+// newConfiguredLogParser uses the pattern sets from file, or the user's config directory when file is empty, falling
+// back to the bundled sets if they can't be loaded
+func newConfiguredLogParser(file string, logger *log.Logger) *LogParser {
+	if file == "" {
+		file, _ = configPath(patternsFile)
+	}
+
+	sets, err := loadPatternSets(file)
+	if err != nil {
+		log.Println("WARN: Failed to load log patterns from " + file + ": " + err.Error())
+
+		sets, _ = loadPatternSets("")
+	}
+
+	return NewLogParser(sets, logger)
+}
+
+func NewLogParser(sets []PatternSet, logger *log.Logger) *LogParser {
+	return &LogParser{
+		Sets:   sets,
+		Set:    &sets[0],
+		Logger: logger,
+	}
+}
+
+func (parser *LogParser) selectSet(version string) {
+	parser.Version = version
+
+	parsed, err := parseGameVersion(version)
+	if err == nil {
+		for i := range parser.Sets {
+			if parser.Sets[i].Supports(parsed) {
+				parser.Set = &parser.Sets[i]
+
+				_ = parser.Logger.Output(2, "Using log patterns "+parser.Set.Name+" for game version "+version)
+
+				return
+			}
+		}
+	}
+
+	parser.Set = &parser.Sets[0]
+
+	log.Println("WARN: No log patterns are known for game version " + version + ", trying " + parser.Set.Name)
+}
+
+func (parser *LogParser) resetSession() {
+	parser.sessionLines = 0
+	parser.sessionMatches = 0
+	parser.warned = false
+}
+
+func (parser *LogParser) checkSession() {
+	if parser.sessionMatches > 0 || parser.warned {
+		return
+	}
+
+	parser.warned = true
+
+	log.Println("WARN: None of the " + parser.Set.Name + " log patterns have matched this session (game version " +
+		parser.Version + "), party sync is probably broken until the patterns are updated")
+}
+
+func (parser *LogParser) Parse(line string) (PartyEvent, bool) {
+	switch {
+	case onLogFileOpen.MatchString(line):
+		// A new log without the last one being closed means the game crashed, so whatever it was doing is over
+		crashed := parser.sessionLines > 0
+
+		parser.resetSession()
+
+		return GameClosedEvent{}, crashed
+	case onLogFileClosed.MatchString(line):
+		if parser.sessionLines > 0 {
+			parser.checkSession()
+		}
+
+		parser.resetSession()
+
+		return GameClosedEvent{}, true
+	}
+
+	if match := onGameVersion.FindStringSubmatch(line); len(match) != 0 {
+		parser.selectSet(match[1])
+		parser.resetSession()
+
+		return nil, false
+	}
+
+	parser.sessionLines++
+
+	for _, pattern := range parser.Set.Patterns {
+		match := pattern.compiled.FindStringSubmatch(line)
+		if len(match) == 0 {
+			continue
+		}
+
+		event, ok := patternEvent(pattern, match)
+		if ok {
+			parser.sessionMatches++
+
+			return event, true
+		}
+	}
+
+	if parser.sessionLines >= patternWarningLines {
+		parser.checkSession()
+	}
+
+	return nil, false
+}
+
+func patternEvent(pattern LogPattern, match []string) (PartyEvent, bool) {
+	groups := map[string]string{}
+
+	for i, name := range pattern.compiled.SubexpNames() {
+		if name != "" {
+			groups[name] = match[i]
+		}
+	}
+
+	switch pattern.Event {
+	case PatternReturnedToMenu:
+		return ReturnedToMenuEvent{}, true
+	case PatternPartyCreated:
+		return PartyCreatedEvent{PartyID: groups["party"]}, true
+	case PatternPartyJoined:
+		return PartyJoinedEvent{PartyID: groups["party"]}, true
+	case PatternPartyLeaderChanged:
+		// Other members' account IDs are shortened, so a full ID means we are the new leader
+		return PartyLeaderChangedEvent{PartyID: groups["party"], Leader: !strings.Contains(groups["leader"], "...")}, true
+	case PatternMatchmakingStatus:
+		return MatchmakingStartedEvent{Status: groups["status"]}, true
+	case PatternMatchJoined:
+		return MatchJoinedEvent{Match: groups["match"], Session: groups["session"]}, true
+	case PatternGamePhase:
+		return GamePhaseEvent{Phase: groups["phase"]}, true
+	case PatternMatchWon:
+		return MatchWonEvent{}, true
+	}
+
+	return nil, false
+}
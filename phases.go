@@ -0,0 +1,116 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+const phaseRulesFile = "phases.json"
+
+// PhaseRule swaps the station behind an in-game station while the match is in a phase
+type PhaseRule struct {
+	Phase       MatchPhase `json:"phase"`
+	ID          string     `json:"id"`
+	StationUser string     `json:"station_user"`
+	StationID   string     `json:"station_id"`
+}
+
+func (rule PhaseRule) Binding() APIBinding {
+	return APIBinding{
+		ID:          rule.ID,
+		StationUser: rule.StationUser,
+		StationID:   rule.StationID,
+	}
+}
+
+type PhaseRules struct {
+	mu    sync.Mutex
+	Rules []PhaseRule
+}
+
+var phaseRules = &PhaseRules{}
+
+func loadPhaseRules() error {
+	phaseRules.mu.Lock()
+	defer phaseRules.mu.Unlock()
+
+	return loadConfig(phaseRulesFile, &phaseRules.Rules)
+}
+
+func isMatchPhase(name string) bool {
+	for _, phase := range matchPhases {
+		if string(phase) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Set replaces any rule for the same phase and in-game station
+func (rules *PhaseRules) Set(rule PhaseRule) error {
+	rules.mu.Lock()
+	defer rules.mu.Unlock()
+
+	updated := []PhaseRule{rule}
+
+	for _, existing := range rules.Rules {
+		if existing.Phase != rule.Phase || existing.ID != rule.ID {
+			updated = append(updated, existing)
+		}
+	}
+
+	sort.Slice(updated, func(i, j int) bool {
+		if updated[i].Phase != updated[j].Phase {
+			return updated[i].Phase < updated[j].Phase
+		}
+
+		return updated[i].ID < updated[j].ID
+	})
+
+	rules.Rules = updated
+
+	return saveConfig(phaseRulesFile, rules.Rules)
+}
+
+func (rules *PhaseRules) Clear(phase MatchPhase, id string) (bool, error) {
+	rules.mu.Lock()
+	defer rules.mu.Unlock()
+
+	var updated []PhaseRule
+
+	for _, existing := range rules.Rules {
+		if existing.Phase != phase || existing.ID != id {
+			updated = append(updated, existing)
+		}
+	}
+
+	if len(updated) == len(rules.Rules) {
+		return false, nil
+	}
+
+	rules.Rules = updated
+
+	return true, saveConfig(phaseRulesFile, rules.Rules)
+}
+
+func (rules *PhaseRules) List() []PhaseRule {
+	rules.mu.Lock()
+	defer rules.mu.Unlock()
+
+	return append([]PhaseRule{}, rules.Rules...)
+}
+
+// Find returns the rule for an in-game station in phase
+func (rules *PhaseRules) Find(phase MatchPhase, id string) (PhaseRule, bool) {
+	rules.mu.Lock()
+	defer rules.mu.Unlock()
+
+	for _, rule := range rules.Rules {
+		if rule.Phase == phase && rule.ID == id {
+			return rule, true
+		}
+	}
+
+	return PhaseRule{}, false
+}
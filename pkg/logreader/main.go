@@ -1,18 +1,39 @@
 package logreader
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/fsnotify/fsnotify"
 )
 
+const (
+	readBufferSize    = 32 * 1024
+	defaultBufferSize = 64
+)
+
 type LogReader struct {
 	file     *os.File
 	fileName string
 	watcher  *fsnotify.Watcher
+	offset   int64
+	partial  string
+	options  options
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
 	Events   chan LogEvent
+
+	// skipPartial drops the first line read, set when reading starts part way through it
+	skipPartial bool
+
+	// finished is the last file read to the end, so it isn't picked up again under the name it was rotated to
+	finished os.FileInfo
 }
 
 type LogEvent struct {
@@ -21,16 +42,71 @@ type LogEvent struct {
 	Error   error
 }
 
-func New(file string) (*LogReader, error) {
+type options struct {
+	fromEnd    bool
+	startAt    func(line string) bool
+	bufferSize int
+	pattern    string
+}
+
+type Option func(*options)
+
+// FromEnd skips everything already in the file, only lines written after the reader starts are sent
+func FromEnd() Option {
+	return func(o *options) {
+		o.fromEnd = true
+	}
+}
+
+// FromLastMatch starts reading at the last line in the file that matches, or at the start of the file if none do
+func FromLastMatch(match func(line string) bool) Option {
+	return func(o *options) {
+		o.startAt = match
+	}
+}
+
+// WithBuffer sets how many events can be waiting to be received before the reader stops reading
+func WithBuffer(size int) Option {
+	return func(o *options) {
+		o.bufferSize = size
+	}
+}
+
+// following makes the reader move on to the newest file matching pattern in the same directory whenever one appears
+func following(pattern string) Option {
+	return func(o *options) {
+		o.pattern = filepath.Clean(pattern)
+	}
+}
+
+func New(file string, opts ...Option) (*LogReader, error) {
+	return NewWithContext(context.Background(), file, opts...)
+}
+
+func NewWithContext(ctx context.Context, file string, opts ...Option) (*LogReader, error) {
+	o := options{
+		bufferSize: defaultBufferSize,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+
 	reader := &LogReader{
-		fileName: file,
+		fileName: filepath.Clean(file),
 		watcher:  watcher,
-		Events:   make(chan LogEvent),
+		options:  o,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		Events:   make(chan LogEvent, o.bufferSize),
 	}
 
 	go reader.start()
@@ -38,86 +114,364 @@ func New(file string) (*LogReader, error) {
 	return reader, nil
 }
 
-func (reader *LogReader) handleLines(initial bool, rawLines string) {
-	split := strings.Split(rawLines, "\r\n")
-	lines := make([]string, 0)
+// Close stops the reader and waits for it to finish, Events is closed once it returns
+func (reader *LogReader) Close() error {
+	reader.cancel()
+
+	err := reader.watcher.Close()
+
+	<-reader.done
+
+	return err
+}
+
+// emit sends an event, returning false if the reader was closed while waiting for it to be received
+func (reader *LogReader) emit(event LogEvent) bool {
+	select {
+	case reader.Events <- event:
+		return true
+	case <-reader.ctx.Done():
+		return false
+	}
+}
+
+// splitLines returns the complete lines in data, keeping anything after the last newline until the rest of the line
+// has been written
+func (reader *LogReader) splitLines(data string) []string {
+	split := strings.Split(reader.partial+data, "\n")
+
+	reader.partial = split[len(split)-1]
+
+	if reader.skipPartial && len(split) > 1 {
+		reader.skipPartial = false
+		split = split[1:]
+	}
+
+	lines := make([]string, 0, len(split)-1)
+
+	for _, line := range split[:len(split)-1] {
+		line = strings.TrimSuffix(line, "\r")
 
-	for _, line := range split {
 		if len(line) > 0 {
 			lines = append(lines, line)
 		}
 	}
 
-	reader.Events <- LogEvent{
-		Initial: initial,
-		Lines:   lines,
+	return lines
+}
+
+// flushPartial emits the unterminated last line, used when the file we were reading from is going away
+func (reader *LogReader) flushPartial() {
+	line := strings.TrimSuffix(reader.partial, "\r")
+
+	reader.partial = ""
+
+	if len(line) > 0 {
+		reader.emit(LogEvent{Lines: []string{line}})
 	}
 }
 
-func (reader *LogReader) handleEvents() {
-	for event := range reader.watcher.Events {
-		if event.Op == fsnotify.Write {
-			stat, err := reader.file.Stat()
-			if err != nil {
-				return
+// read sends everything written since the last read, a buffer at a time so large files don't have to fit in memory
+func (reader *LogReader) read(initial bool) error {
+	buf := make([]byte, readBufferSize)
+
+	for {
+		n, err := reader.file.Read(buf)
+		if n > 0 {
+			reader.offset += int64(n)
+
+			lines := reader.splitLines(string(buf[:n]))
+			if len(lines) > 0 && !reader.emit(LogEvent{Initial: initial, Lines: lines}) {
+				return reader.ctx.Err()
 			}
+		}
 
-			offset, err := reader.file.Seek(0, io.SeekCurrent)
-			if err != nil {
-				return
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (reader *LogReader) open() error {
+	file, err := os.Open(reader.fileName)
+	if err != nil {
+		return err
+	}
+
+	reader.closeFile()
+
+	reader.file = file
+	reader.offset = 0
+	reader.partial = ""
+	reader.skipPartial = false
+
+	return nil
+}
+
+func (reader *LogReader) closeFile() {
+	if reader.file != nil {
+		_ = reader.file.Close()
+		reader.file = nil
+	}
+}
+
+// findStart returns the offset of the last line matching the start option, scanning the file a line at a time
+func (reader *LogReader) findStart() (int64, error) {
+	scanner := bufio.NewReaderSize(reader.file, readBufferSize)
+
+	var offset, start int64
+
+	for {
+		line, err := scanner.ReadString('\n')
+
+		if reader.options.startAt(strings.TrimRight(line, "\r\n")) {
+			start = offset
+		}
+
+		offset += int64(len(line))
+
+		if errors.Is(err, io.EOF) {
+			return start, nil
+		}
+
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// seekStart moves the open file to where the options say reading should begin
+func (reader *LogReader) seekStart() error {
+	var offset int64
+
+	switch {
+	case reader.options.fromEnd:
+		stat, err := reader.file.Stat()
+		if err != nil {
+			return err
+		}
+
+		offset = stat.Size()
+
+		// Starting in the middle of a line, the rest of it is dropped rather than sent as if it were a whole line
+		last := make([]byte, 1)
+		if _, err := reader.file.ReadAt(last, offset-1); err == nil && last[0] != '\n' {
+			reader.skipPartial = true
+		}
+	case reader.options.startAt != nil:
+		var err error
+
+		offset, err = reader.findStart()
+		if err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	_, err := reader.file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	reader.offset = offset
+
+	return nil
+}
+
+// replaced reports whether the file at our path is no longer the one we have open, or has been truncated
+func (reader *LogReader) replaced() bool {
+	stat, err := os.Stat(reader.fileName)
+	if err != nil {
+		return false
+	}
+
+	current, err := reader.file.Stat()
+	if err != nil {
+		return true
+	}
+
+	return !os.SameFile(stat, current) || stat.Size() < reader.offset
+}
+
+// current reports whether the file we have open is still the one at our path
+func (reader *LogReader) current() bool {
+	stat, err := os.Stat(reader.fileName)
+	if err != nil {
+		return false
+	}
+
+	open, err := reader.file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(stat, open)
+}
+
+// finishFile sends whatever is left in the file we have open before it's closed
+func (reader *LogReader) finishFile() {
+	if reader.file == nil {
+		return
+	}
+
+	_ = reader.read(false)
+
+	reader.flushPartial()
+
+	if stat, err := reader.file.Stat(); err == nil {
+		reader.finished = stat
+	}
+
+	reader.closeFile()
+}
+
+func (reader *LogReader) handleChange() error {
+	if reader.file == nil || reader.replaced() {
+		reader.finishFile()
+
+		err := reader.open()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return reader.read(false)
+}
+
+// reglob switches to the newest file matching the pattern, unless that's the one we are reading or have finished
+func (reader *LogReader) reglob() error {
+	files, err := filepath.Glob(reader.options.pattern)
+	if err != nil {
+		return err
+	}
+
+	sortByModTime(files)
+
+	for i := len(files) - 1; i >= 0; i-- {
+		stat, err := os.Stat(files[i])
+		if err != nil || reader.finished != nil && os.SameFile(stat, reader.finished) {
+			continue
+		}
+
+		if reader.file != nil {
+			if open, err := reader.file.Stat(); err == nil && os.SameFile(stat, open) {
+				return nil
 			}
+		}
+
+		reader.finishFile()
+		reader.fileName = filepath.Clean(files[i])
+
+		return reader.handleChange()
+	}
+
+	return nil
+}
+
+// isMatch reports whether name is another file matching the pattern being followed
+func (reader *LogReader) isMatch(name string) bool {
+	if reader.options.pattern == "" || reader.isLogFile(name) {
+		return false
+	}
+
+	matched, _ := filepath.Match(reader.options.pattern, filepath.Clean(name))
+
+	return matched
+}
 
-			if stat.Size() < offset {
-				_, err := reader.file.Seek(0, io.SeekStart)
+func (reader *LogReader) isLogFile(name string) bool {
+	return strings.EqualFold(filepath.Clean(name), reader.fileName)
+}
+
+func (reader *LogReader) handleEvents() error {
+	for {
+		select {
+		case <-reader.ctx.Done():
+			return nil
+		case event, ok := <-reader.watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if reader.isMatch(event.Name) && event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+				err := reader.reglob()
 				if err != nil {
-					return
+					return err
 				}
+
+				continue
+			}
+
+			if !reader.isLogFile(event.Name) {
+				continue
+			}
+
+			if event.Op&(fsnotify.Rename|fsnotify.Remove) != 0 {
+				// The game rotates its log by renaming it, whatever is left in the old file still belongs to this log.
+				// By the time the event arrives we may already have moved on to the new file, which is left alone.
+				if reader.file != nil && !reader.current() {
+					reader.finishFile()
+				}
+
+				continue
 			}
 
-			file, err := io.ReadAll(reader.file)
+			err := reader.handleChange()
 			if err != nil {
-				return
+				return err
+			}
+		case err, ok := <-reader.watcher.Errors:
+			if !ok {
+				return nil
 			}
 
-			reader.handleLines(false, string(file))
+			return err
 		}
 	}
 }
 
-func (reader *LogReader) start() {
-	var err error
-
-	reader.file, err = os.Open(reader.fileName)
+func (reader *LogReader) run() error {
+	err := reader.watcher.Add(filepath.Dir(reader.fileName))
 	if err != nil {
-		reader.Events <- LogEvent{
-			Error: err,
-		}
-		close(reader.Events)
-
-		return
+		return err
 	}
 
-	err = reader.watcher.Add(reader.fileName)
+	err = reader.open()
 	if err != nil {
-		reader.Events <- LogEvent{
-			Error: err,
-		}
-		close(reader.Events)
-
-		return
+		return err
 	}
 
-	initial, err := io.ReadAll(reader.file)
+	err = reader.seekStart()
 	if err != nil {
-		reader.Events <- LogEvent{
-			Error: err,
-		}
-		close(reader.Events)
+		return err
+	}
 
-		return
+	err = reader.read(true)
+	if err != nil {
+		return err
 	}
 
-	reader.handleLines(true, string(initial))
+	return reader.handleEvents()
+}
+
+func (reader *LogReader) start() {
+	defer close(reader.done)
+	defer close(reader.Events)
+	defer reader.closeFile()
 
-	reader.handleEvents()
+	err := reader.run()
+	if err != nil && reader.ctx.Err() == nil {
+		reader.emit(LogEvent{
+			Error: err,
+		})
+	}
 }
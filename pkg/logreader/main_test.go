@@ -0,0 +1,178 @@
+package logreader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// collect reads lines from source until it has want of them, then makes sure nothing else turns up
+func collect(t *testing.T, source Source, want []string) {
+	t.Helper()
+
+	var got []string
+
+	timeout := time.After(5 * time.Second)
+
+	for len(got) < len(want) {
+		select {
+		case event := <-source.Lines():
+			if event.Error != nil {
+				t.Fatal(event.Error)
+			}
+
+			got = append(got, event.Lines...)
+		case <-timeout:
+			t.Fatalf("timed out with %q, want %q", got, want)
+		}
+	}
+
+	select {
+	case event := <-source.Lines():
+		got = append(got, event.Lines...)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func appendFile(t *testing.T, file string, data string) {
+	t.Helper()
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.WriteString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newReader(t *testing.T, file string, opts ...Option) *LogReader {
+	t.Helper()
+
+	reader, err := New(file, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = reader.Close()
+	})
+
+	return reader
+}
+
+func TestPartialLines(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "FortniteGame.log")
+
+	appendFile(t, file, "first\r\nsec")
+
+	reader := newReader(t, file)
+
+	collect(t, reader, []string{"first"})
+
+	appendFile(t, file, "ond\r\nthi")
+	collect(t, reader, []string{"second"})
+
+	appendFile(t, file, "rd\r\n")
+	collect(t, reader, []string{"third"})
+}
+
+func TestFromEndSkipsPartialLine(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "FortniteGame.log")
+
+	appendFile(t, file, "old\r\nhalf of a li")
+
+	reader := newReader(t, file, FromEnd())
+
+	// Give the reader a moment to seek to the end before more is written
+	time.Sleep(100 * time.Millisecond)
+
+	appendFile(t, file, "ne\r\nnew\r\n")
+	collect(t, reader, []string{"new"})
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "FortniteGame.log")
+
+	appendFile(t, file, "one\r\n")
+
+	reader := newReader(t, file)
+
+	collect(t, reader, []string{"one"})
+
+	// The game renames its log to a backup and starts a new one, anything written to the old file before the rename
+	// still belongs to the log
+	appendFile(t, file, "two\r\nunterminated")
+
+	if err := os.Rename(file, filepath.Join(dir, "FortniteGame-backup.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	appendFile(t, file, "three\r\n")
+	collect(t, reader, []string{"two", "unterminated", "three"})
+
+	appendFile(t, file, "four\r\n")
+	collect(t, reader, []string{"four"})
+}
+
+func TestTruncation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "FortniteGame.log")
+
+	appendFile(t, file, "one\r\ntwo\r\n")
+
+	reader := newReader(t, file)
+
+	collect(t, reader, []string{"one", "two"})
+
+	if err := os.WriteFile(file, []byte("new\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	collect(t, reader, []string{"new"})
+
+	appendFile(t, file, "more\r\n")
+	collect(t, reader, []string{"more"})
+}
+
+func TestFromLastMatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "FortniteGame.log")
+
+	appendFile(t, file, "Log file open\r\nold\r\nLog file open\r\ncurrent\r\n")
+
+	reader := newReader(t, file, FromLastMatch(func(line string) bool {
+		return line == "Log file open"
+	}))
+
+	collect(t, reader, []string{"Log file open", "current"})
+}
+
+func TestCloseEndsEvents(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "FortniteGame.log")
+
+	appendFile(t, file, "one\r\n")
+
+	reader, err := New(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for range reader.Events {
+	}
+}
@@ -0,0 +1,370 @@
+package logreader
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const maxLineSize = 1024 * 1024
+
+// Source is anything game log lines can be read from
+type Source interface {
+	Lines() <-chan LogEvent
+	Close() error
+}
+
+func (reader *LogReader) Lines() <-chan LogEvent {
+	return reader.Events
+}
+
+// Open picks a source from spec: "-" or "stdin" for standard input, "tcp://host:port" or "udp://host:port" to listen
+// for lines, a glob pattern for a set of rotated logs or otherwise a single file to tail. Options only apply to files.
+func Open(ctx context.Context, spec string, opts ...Option) (Source, error) {
+	switch {
+	case spec == "-" || spec == "stdin":
+		return NewStreamSource(ctx, os.Stdin), nil
+	case strings.HasPrefix(spec, "tcp://"):
+		return Listen(ctx, "tcp", strings.TrimPrefix(spec, "tcp://"))
+	case strings.HasPrefix(spec, "udp://"):
+		return Listen(ctx, "udp", strings.TrimPrefix(spec, "udp://"))
+	case strings.ContainsAny(spec, "*?["):
+		return NewGlobSource(ctx, spec, opts...)
+	default:
+		return NewWithContext(ctx, spec, opts...)
+	}
+}
+
+// StreamSource reads lines from a reader until it ends, such as stdin
+type StreamSource struct {
+	reader io.Reader
+	ctx    context.Context
+	cancel context.CancelFunc
+	events chan LogEvent
+}
+
+func NewStreamSource(ctx context.Context, reader io.Reader) *StreamSource {
+	ctx, cancel := context.WithCancel(ctx)
+
+	source := &StreamSource{
+		reader: reader,
+		ctx:    ctx,
+		cancel: cancel,
+		events: make(chan LogEvent, defaultBufferSize),
+	}
+
+	go source.start()
+
+	return source
+}
+
+func (source *StreamSource) Lines() <-chan LogEvent {
+	return source.events
+}
+
+// Close stops sending lines, a read that is already blocked on the reader isn't interrupted
+func (source *StreamSource) Close() error {
+	source.cancel()
+
+	return nil
+}
+
+func (source *StreamSource) start() {
+	defer close(source.events)
+
+	err := scanLines(source.ctx, source.reader, source.events)
+	if err != nil && source.ctx.Err() == nil {
+		sendEvent(source.ctx, source.events, LogEvent{Error: err})
+	}
+}
+
+func sendEvent(ctx context.Context, events chan<- LogEvent, event LogEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func scanLines(ctx context.Context, reader io.Reader, events chan<- LogEvent) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, readBufferSize), maxLineSize)
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(line) == 0 {
+			continue
+		}
+
+		if !sendEvent(ctx, events, LogEvent{Lines: []string{line}}) {
+			return ctx.Err()
+		}
+	}
+
+	return scanner.Err()
+}
+
+// NetSource listens for lines sent over TCP connections or UDP datagrams, for feeding a game running elsewhere
+type NetSource struct {
+	listener net.Listener
+	conn     net.PacketConn
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	events   chan LogEvent
+}
+
+func Listen(ctx context.Context, network string, address string) (*NetSource, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	source := &NetSource{
+		ctx:    ctx,
+		cancel: cancel,
+		events: make(chan LogEvent, defaultBufferSize),
+	}
+
+	var err error
+
+	switch network {
+	case "tcp":
+		source.listener, err = net.Listen(network, address)
+		if err == nil {
+			source.wg.Add(1)
+
+			go source.acceptTCP()
+		}
+	case "udp":
+		source.conn, err = net.ListenPacket(network, address)
+		if err == nil {
+			source.wg.Add(1)
+
+			go source.readUDP()
+		}
+	default:
+		err = errors.New("unsupported network " + network)
+	}
+
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		source.closeListeners()
+		source.wg.Wait()
+
+		close(source.events)
+	}()
+
+	return source, nil
+}
+
+func (source *NetSource) Lines() <-chan LogEvent {
+	return source.events
+}
+
+func (source *NetSource) Close() error {
+	source.cancel()
+
+	return nil
+}
+
+func (source *NetSource) Addr() net.Addr {
+	if source.listener != nil {
+		return source.listener.Addr()
+	}
+
+	return source.conn.LocalAddr()
+}
+
+func (source *NetSource) closeListeners() {
+	if source.listener != nil {
+		_ = source.listener.Close()
+	}
+
+	if source.conn != nil {
+		_ = source.conn.Close()
+	}
+}
+
+func (source *NetSource) acceptTCP() {
+	defer source.wg.Done()
+
+	for {
+		conn, err := source.listener.Accept()
+		if err != nil {
+			if source.ctx.Err() == nil {
+				sendEvent(source.ctx, source.events, LogEvent{Error: err})
+				source.cancel()
+			}
+
+			return
+		}
+
+		source.wg.Add(1)
+
+		go func() {
+			defer source.wg.Done()
+
+			// Closing the connection is the only way to stop a blocked read, this stops once the sender hangs up
+			closed := make(chan struct{})
+
+			go func() {
+				select {
+				case <-source.ctx.Done():
+					_ = conn.Close()
+				case <-closed:
+				}
+			}()
+
+			_ = scanLines(source.ctx, conn, source.events)
+
+			close(closed)
+
+			_ = conn.Close()
+		}()
+	}
+}
+
+func (source *NetSource) readUDP() {
+	defer source.wg.Done()
+
+	buf := make([]byte, 64*1024)
+
+	for {
+		n, _, err := source.conn.ReadFrom(buf)
+		if err != nil {
+			if source.ctx.Err() == nil {
+				sendEvent(source.ctx, source.events, LogEvent{Error: err})
+				source.cancel()
+			}
+
+			return
+		}
+
+		_ = scanLines(source.ctx, strings.NewReader(string(buf[:n])), source.events)
+	}
+}
+
+// GlobSource sends every file matching a pattern oldest first, then keeps tailing the newest one. When a newer file
+// appears next to it, the rest of the current one is sent and the new one is tailed from its start.
+type GlobSource struct {
+	tail   *LogReader
+	ctx    context.Context
+	cancel context.CancelFunc
+	events chan LogEvent
+}
+
+func NewGlobSource(ctx context.Context, pattern string, opts ...Option) (*GlobSource, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no files match " + pattern)
+	}
+
+	sortByModTime(files)
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	tail, err := NewWithContext(ctx, files[len(files)-1], append(opts, following(pattern))...)
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	source := &GlobSource{
+		tail:   tail,
+		ctx:    ctx,
+		cancel: cancel,
+		events: make(chan LogEvent, defaultBufferSize),
+	}
+
+	go source.start(files[:len(files)-1])
+
+	return source, nil
+}
+
+func sortByModTime(files []string) {
+	modTimes := map[string]int64{}
+
+	for _, file := range files {
+		if stat, err := os.Stat(file); err == nil {
+			modTimes[file] = stat.ModTime().UnixNano()
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return modTimes[files[i]] < modTimes[files[j]]
+	})
+}
+
+func (source *GlobSource) Lines() <-chan LogEvent {
+	return source.events
+}
+
+func (source *GlobSource) Close() error {
+	source.cancel()
+
+	return source.tail.Close()
+}
+
+func (source *GlobSource) start(older []string) {
+	defer close(source.events)
+
+	for _, name := range older {
+		err := source.sendFile(name)
+		if err != nil {
+			sendEvent(source.ctx, source.events, LogEvent{Error: err})
+			return
+		}
+	}
+
+	for event := range source.tail.Events {
+		if !sendEvent(source.ctx, source.events, event) {
+			return
+		}
+	}
+}
+
+func (source *GlobSource) sendFile(name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	lines := make(chan LogEvent)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- scanLines(source.ctx, file, lines)
+
+		close(lines)
+	}()
+
+	for event := range lines {
+		event.Initial = true
+
+		if !sendEvent(source.ctx, source.events, event) {
+			break
+		}
+	}
+
+	return <-done
+}
@@ -0,0 +1,119 @@
+package logreader
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func newGlobSource(t *testing.T, pattern string) *GlobSource {
+	t.Helper()
+
+	source, err := NewGlobSource(context.Background(), pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = source.Close()
+	})
+
+	return source
+}
+
+// setModTime spaces the files out, as the filesystem may not tell apart files written within the same tick
+func setModTime(t *testing.T, file string, ago time.Duration) {
+	t.Helper()
+
+	modTime := time.Now().Add(-ago)
+
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGlobSourceNewFile(t *testing.T) {
+	dir := t.TempDir()
+
+	appendFile(t, filepath.Join(dir, "game-1.log"), "one\r\n")
+	setModTime(t, filepath.Join(dir, "game-1.log"), 2*time.Minute)
+	appendFile(t, filepath.Join(dir, "game-2.log"), "two\r\n")
+	setModTime(t, filepath.Join(dir, "game-2.log"), time.Minute)
+
+	source := newGlobSource(t, filepath.Join(dir, "game-*.log"))
+
+	collect(t, source, []string{"one", "two"})
+
+	// The game moves on to a file with a new name, the rest of the old one is sent before the new one
+	appendFile(t, filepath.Join(dir, "game-2.log"), "two and a half\r\n")
+	appendFile(t, filepath.Join(dir, "game-3.log"), "three\r\n")
+	collect(t, source, []string{"two and a half", "three"})
+
+	appendFile(t, filepath.Join(dir, "game-3.log"), "four\r\n")
+	collect(t, source, []string{"four"})
+}
+
+func TestGlobSourceRenamedFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "FortniteGame.log")
+
+	appendFile(t, file, "one\r\n")
+
+	source := newGlobSource(t, filepath.Join(dir, "FortniteGame*.log"))
+
+	collect(t, source, []string{"one"})
+
+	// The backup matches the pattern too, it mustn't be sent again
+	appendFile(t, file, "two\r\n")
+
+	if err := os.Rename(file, filepath.Join(dir, "FortniteGame-backup.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	appendFile(t, file, "three\r\n")
+	collect(t, source, []string{"two", "three"})
+}
+
+func TestNetSourceConnectionEnds(t *testing.T) {
+	source, err := Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = source.Close()
+	})
+
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 10; i++ {
+		conn, err := net.Dial("tcp", source.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = conn.Write([]byte("line\r\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_ = conn.Close()
+
+		collect(t, source, []string{"line"})
+	}
+
+	// Every connection has hung up, nothing should be left waiting for the source to close
+	deadline := time.Now().Add(5 * time.Second)
+
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left running for closed connections", runtime.NumGoroutine()-before)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
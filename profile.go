@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Profile struct {
+	Stations []APIStation     `json:"stations" yaml:"stations"`
+	Bindings []ProfileBinding `json:"bindings" yaml:"bindings"`
+}
+
+// ProfileBinding refers to the in-game station by name so profiles can be shared, an empty StationUser means the
+// user applying the profile
+type ProfileBinding struct {
+	InGameStation string `json:"in_game_station" yaml:"in_game_station"`
+	StationUser   string `json:"station_user,omitempty" yaml:"station_user,omitempty"`
+	StationID     string `json:"station_id" yaml:"station_id"`
+}
+
+const (
+	PlanCreateStation = "create"
+	PlanUpdateStation = "update"
+	PlanDeleteStation = "delete"
+	PlanBind          = "bind"
+	PlanUnbind        = "unbind"
+)
+
+type PlanAction struct {
+	Action  string
+	Station APIStation
+	Binding APIBinding
+}
+
+func (action PlanAction) String() string {
+	switch action.Action {
+	case PlanCreateStation:
+		return "+ station " + action.Station.ID + " (" + strings.TrimSpace(action.Station.Type+" "+action.Station.Source) + ")"
+	case PlanUpdateStation:
+		return "~ station " + action.Station.ID + " (" + strings.TrimSpace(action.Station.Type+" "+action.Station.Source) + ")"
+	case PlanDeleteStation:
+		return "- station " + action.Station.ID
+	case PlanBind:
+		return "+ bind " + getInGameStationName(action.Binding.ID) + " -> " + action.Binding.StationUser + ":" + action.Binding.StationID
+	case PlanUnbind:
+		return "- bind " + getInGameStationName(action.Binding.ID)
+	}
+
+	return action.Action
+}
+
+func isYAMLFile(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func loadProfile(file string) (Profile, error) {
+	var profile Profile
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return profile, err
+	}
+
+	if isYAMLFile(file) {
+		err = yaml.Unmarshal(data, &profile)
+	} else {
+		err = json.Unmarshal(data, &profile)
+	}
+
+	return profile, err
+}
+
+func saveProfile(file string, profile Profile) error {
+	var data []byte
+
+	var err error
+
+	if isYAMLFile(file) {
+		data, err = yaml.Marshal(profile)
+	} else {
+		data, err = json.MarshalIndent(profile, "", "  ")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, data, 0600)
+}
+
+func resolveInGameStation(nameOrID string) (InGameStation, bool) {
+	if station, ok := getInGameStationByName(nameOrID); ok {
+		return station, true
+	}
+
+	for _, station := range inGameStations {
+		if station.ID == nameOrID {
+			return station, true
+		}
+	}
+
+	return InGameStation{}, false
+}
+
+func exportProfile(user APIUser, self string) Profile {
+	profile := Profile{
+		Stations: make([]APIStation, 0, len(user.Stations)),
+		Bindings: make([]ProfileBinding, 0, len(user.Bindings)),
+	}
+
+	for _, station := range user.Stations {
+		profile.Stations = append(profile.Stations, station)
+	}
+
+	for _, binding := range user.Bindings {
+		profileBinding := ProfileBinding{
+			InGameStation: getInGameStationName(binding.ID),
+			StationUser:   binding.StationUser,
+			StationID:     binding.StationID,
+		}
+
+		if profileBinding.StationUser == self {
+			profileBinding.StationUser = ""
+		}
+
+		profile.Bindings = append(profile.Bindings, profileBinding)
+	}
+
+	sort.Slice(profile.Stations, func(i, j int) bool {
+		return profile.Stations[i].ID < profile.Stations[j].ID
+	})
+
+	sort.Slice(profile.Bindings, func(i, j int) bool {
+		return profile.Bindings[i].InGameStation < profile.Bindings[j].InGameStation
+	})
+
+	return profile
+}
+
+// planProfile works out what needs to change for user to match profile, with prune set anything not in the profile
+// is removed as well
+func planProfile(user APIUser, self string, profile Profile, prune bool) ([]PlanAction, error) {
+	var actions []PlanAction
+
+	stations := map[string]bool{}
+
+	for _, station := range profile.Stations {
+		if station.ID == "" {
+			return nil, errors.New("profile contains a station without an id")
+		}
+
+		if station.Type != StationTypeStatic && station.Type != StationTypeStream {
+			return nil, fmt.Errorf("station %s has unknown type %q", station.ID, station.Type)
+		}
+
+		stations[station.ID] = true
+
+		existing, ok := user.Stations[station.ID]
+		if !ok {
+			actions = append(actions, PlanAction{Action: PlanCreateStation, Station: station})
+		} else if existing != station {
+			actions = append(actions, PlanAction{Action: PlanUpdateStation, Station: station})
+		}
+	}
+
+	bindings := map[string]bool{}
+
+	for _, profileBinding := range profile.Bindings {
+		inGameStation, ok := resolveInGameStation(profileBinding.InGameStation)
+		if !ok {
+			return nil, fmt.Errorf("unknown in-game station %q", profileBinding.InGameStation)
+		}
+
+		binding := APIBinding{
+			ID:          inGameStation.ID,
+			StationUser: profileBinding.StationUser,
+			StationID:   profileBinding.StationID,
+		}
+
+		if binding.StationUser == "" {
+			binding.StationUser = self
+		}
+
+		if binding.StationUser == self && !stations[binding.StationID] {
+			if _, ok := user.Stations[binding.StationID]; !ok || prune {
+				return nil, fmt.Errorf("%s is bound to unknown station %s", inGameStation.Name, binding.StationID)
+			}
+		}
+
+		bindings[binding.ID] = true
+
+		if existing, ok := user.Bindings[binding.ID]; !ok || existing != binding {
+			actions = append(actions, PlanAction{Action: PlanBind, Binding: binding})
+		}
+	}
+
+	if prune {
+		actions = append(actions, planRemovals(user, stations, bindings)...)
+	}
+
+	return actions, nil
+}
+
+func planRemovals(user APIUser, stations map[string]bool, bindings map[string]bool) []PlanAction {
+	var unbinds, deletes []PlanAction
+
+	for _, binding := range user.Bindings {
+		if !bindings[binding.ID] {
+			unbinds = append(unbinds, PlanAction{Action: PlanUnbind, Binding: binding})
+		}
+	}
+
+	for _, station := range user.Stations {
+		if !stations[station.ID] {
+			deletes = append(deletes, PlanAction{Action: PlanDeleteStation, Station: station})
+		}
+	}
+
+	sort.Slice(unbinds, func(i, j int) bool {
+		return unbinds[i].Binding.ID < unbinds[j].Binding.ID
+	})
+
+	sort.Slice(deletes, func(i, j int) bool {
+		return deletes[i].Station.ID < deletes[j].Station.ID
+	})
+
+	return append(unbinds, deletes...)
+}
+
+// executePlan runs each action against the API, keeping user in sync as it goes. Callers print the plan first, so
+// only how far it has got is printed here.
+func executePlan(api *APIClient, user APIUser, actions []PlanAction) error {
+	for i, action := range actions {
+		var err error
+
+		switch action.Action {
+		case PlanCreateStation, PlanUpdateStation:
+			err = api.CreateStation(action.Station)
+			if err == nil {
+				user.Stations[action.Station.ID] = action.Station
+			}
+		case PlanDeleteStation:
+			err = api.DeleteStation(action.Station)
+			if err == nil {
+				delete(user.Stations, action.Station.ID)
+			}
+		case PlanBind:
+			err = api.CreateBinding(action.Binding)
+			if err == nil {
+				user.Bindings[action.Binding.ID] = action.Binding
+			}
+		case PlanUnbind:
+			err = api.DeleteBinding(action.Binding)
+			if err == nil {
+				delete(user.Bindings, action.Binding.ID)
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", action, err)
+		}
+
+		fmt.Printf("Applied %d/%d\n", i+1, len(actions))
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// migrateRegistrySecrets moves the API credentials and the CA out of the registry, each value is only
+// removed once it has been saved to store
+func migrateRegistrySecrets(store CredentialStore) error {
+	k, err := registry.OpenKey(registry.CURRENT_USER, `SOFTWARE\FNRadio`, registry.QUERY_VALUE|registry.SET_VALUE)
+	if errors.Is(err, registry.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer k.Close()
+
+	names, err := k.ReadValueNames(0)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		var value []byte
+
+		switch {
+		case strings.HasPrefix(name, "APICredentials:"):
+			var s string
+
+			s, _, err = k.GetStringValue(name)
+			value = []byte(s)
+		case name == sslPrivateKeyCredential || name == sslCertificateCredential:
+			value, _, err = k.GetBinaryValue(name)
+		default:
+			continue
+		}
+
+		if err == nil {
+			err = store.Save(name, value)
+		}
+
+		if err == nil {
+			err = k.DeleteValue(name)
+		}
+
+		if err != nil {
+			return fmt.Errorf("moving %s out of the registry: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// registryHasSecret reports whether an older version left name in the registry and it hasn't been moved out yet
+func registryHasSecret(name string) bool {
+	k, err := registry.OpenKey(registry.CURRENT_USER, `SOFTWARE\FNRadio`, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+
+	defer k.Close()
+
+	_, _, err = k.GetValue(name, nil)
+
+	return err == nil
+}
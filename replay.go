@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	replaySelfID   = "replay-self"
+	replayLeaderID = "replay-leader"
+)
+
+var logTimestamp = regexp.MustCompile(`^\[(\d{4}\.\d{2}\.\d{2}-\d{2}\.\d{2}\.\d{2}:\d{3})]`)
+
+// fakePartyAPI answers the party endpoints the way the real API would for a party where every member runs FNRadio,
+// printing each request so replays show what would have been sent
+type fakePartyAPI struct{}
+
+func (api fakePartyAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/users/@me/party":
+		var party Party
+
+		err := json.NewDecoder(r.Body).Decode(&party)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		fmt.Printf("  API: SetParty id=%s leader=%t match=%s session=%s\n", party.ID, party.Leader, party.Match, party.Session)
+
+		if party.Match == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		response := PartyResponse{
+			Leader: replayLeaderID,
+			Members: []PartyMember{
+				{ID: replaySelfID, Leader: party.Leader},
+				{ID: replayLeaderID, Leader: !party.Leader},
+			},
+		}
+
+		if party.Leader {
+			response.Leader = replaySelfID
+		}
+
+		_ = json.NewEncoder(w).Encode(response)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/users/"):
+		fmt.Printf("  API: GetUser %s\n", strings.TrimPrefix(r.URL.Path, "/users/"))
+
+		_ = json.NewEncoder(w).Encode(APIUser{Stations: map[string]APIStation{}, Bindings: map[string]APIBinding{}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: "not found"})
+	}
+}
+
+func parseLogTimestamp(line string) (time.Time, bool) {
+	match := logTimestamp.FindStringSubmatch(line)
+	if len(match) == 0 {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse("2006.01.02-15.04.05:000", match[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+func replaySubcommand(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	speed := flags.Float64("speed", 0, "playback speed, 1 is real time and 0 is as fast as possible")
+	patterns := flags.String("patterns", "", "a log pattern file to use instead of the configured one")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: fnradio replay [-speed <multiplier>] [-patterns <file>] <log file>")
+		return 2
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	defer file.Close()
+
+	server := httptest.NewServer(fakePartyAPI{})
+	defer server.Close()
+
+	APIRoot = server.URL
+
+	client = &FNRadioClient{
+		APIClient: APIClient{ID: replaySelfID, Secret: "replay"},
+		Users:     map[string]APIUser{replaySelfID: {}},
+		BoundUser: replaySelfID,
+		Logger:    log.New(os.Stdout, "", 0),
+	}
+
+	client.LogParser = newConfiguredLogParser(*patterns, client.Logger)
+	client.Events = &EventBus{}
+
+	client.Events.Subscribe(client.syncParty)
+
+	err = replayLog(file, *speed)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	fmt.Printf("Final state: %s, party %q, match %q, using stations of %s (%s)\n", client.PartyTracker.State,
+		client.PartyTracker.Party.ID, client.PartyTracker.Party.Match, client.BoundUser, client.PartyStatus.Reason)
+
+	return 0
+}
+
+func replayLog(file *os.File, speed float64) error {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var last time.Time
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if t, ok := parseLogTimestamp(line); ok {
+			if speed > 0 && !last.IsZero() && t.After(last) {
+				time.Sleep(time.Duration(float64(t.Sub(last)) / speed))
+			}
+
+			last = t
+		}
+
+		client.handleGameLogLines([]string{line})
+	}
+
+	return scanner.Err()
+}
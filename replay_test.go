@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateFixtures = flag.Bool("update", false, "rewrite the expected events of the log fixtures")
+
+// TestLogFixtures parses every log in testdata/logs and compares the events with the .events file next to it
+func TestLogFixtures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "logs", "*", "*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) == 0 {
+		t.Fatal("no log fixtures found")
+	}
+
+	for _, file := range files {
+		file := file
+
+		name, _ := filepath.Rel(filepath.Join("testdata", "logs"), file)
+
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			var lines []string
+
+			for _, event := range fixtureEvents(t, name) {
+				data, err := json.Marshal(event)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				lines = append(lines, event.Type()+" "+string(data))
+			}
+
+			got := strings.Join(lines, "\n") + "\n"
+			expectedFile := strings.TrimSuffix(file, ".log") + ".events"
+
+			if *updateFixtures {
+				if err := os.WriteFile(expectedFile, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			expected, err := os.ReadFile(expectedFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != string(expected) {
+				t.Errorf("events don't match %s\ngot:\n%s\nwant:\n%s", expectedFile, got, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	LayerPhase    = "phase"
+	LayerSchedule = "schedule"
+	LayerLeader   = "leader"
+	LayerLocal    = "local"
+	LayerOwn      = "own"
+	LayerDefault  = "default"
+)
+
+var defaultBindingOrder = []string{LayerPhase, LayerSchedule, LayerLeader, LayerLocal, LayerOwn, LayerDefault}
+
+// defaultBindings comes from the team-wide default profile, an empty StationUser means our own station
+var defaultBindings = map[string]APIBinding{}
+
+func isBindingLayer(layer string) bool {
+	for _, l := range defaultBindingOrder {
+		if l == layer {
+			return true
+		}
+	}
+
+	return false
+}
+
+func bindingOrder() []string {
+	if len(settings.BindingOrder) == 0 {
+		return defaultBindingOrder
+	}
+
+	return settings.BindingOrder
+}
+
+func loadDefaultProfile() error {
+	if settings.DefaultProfile == "" {
+		return nil
+	}
+
+	profile, err := loadProfile(settings.DefaultProfile)
+	if err != nil {
+		return err
+	}
+
+	bindings := map[string]APIBinding{}
+
+	for _, profileBinding := range profile.Bindings {
+		inGameStation, ok := resolveInGameStation(profileBinding.InGameStation)
+		if !ok {
+			return fmt.Errorf("unknown in-game station %q", profileBinding.InGameStation)
+		}
+
+		bindings[inGameStation.ID] = APIBinding{
+			ID:          inGameStation.ID,
+			StationUser: profileBinding.StationUser,
+			StationID:   profileBinding.StationID,
+		}
+	}
+
+	defaultBindings = bindings
+
+	return nil
+}
+
+// Resolution is the binding an in-game station resolved to, with what each layer that was checked had to say
+type Resolution struct {
+	Layer   string
+	Binding APIBinding
+	Steps   []string
+}
+
+func (client *FNRadioClient) resolveLayer(layer string, id string) (APIBinding, string, bool) {
+	switch layer {
+	case LayerPhase:
+		phase := client.PartyTracker.CurrentPhase()
+
+		if rule, ok := phaseRules.Find(phase, id); ok {
+			return rule.Binding(), "rule for the " + string(phase) + " phase", true
+		}
+
+		return APIBinding{}, "no rule for the " + string(phase) + " phase", false
+	case LayerSchedule:
+		if binding, ok := scheduler.Active(id); ok {
+			return binding, "a schedule is active", true
+		}
+
+		return APIBinding{}, "no active schedule", false
+	case LayerLeader:
+		if client.BoundUser == client.APIClient.ID {
+			return APIBinding{}, "not following a party leader (" + client.PartyStatus.Reason + ")", false
+		}
+
+		if binding, ok := client.Users[client.BoundUser].Bindings[id]; ok {
+			return binding, "bound by party leader " + client.BoundUser, true
+		}
+
+		return APIBinding{}, "party leader " + client.BoundUser + " hasn't bound it", false
+	case LayerLocal:
+		if binding, ok := localBindings.Get(id); ok {
+			return binding, "bound locally", true
+		}
+
+		return APIBinding{}, "no local binding", false
+	case LayerOwn:
+		if binding, ok := client.Users[client.APIClient.ID].Bindings[id]; ok {
+			return binding, "bound by you", true
+		}
+
+		return APIBinding{}, "you haven't bound it", false
+	case LayerDefault:
+		if binding, ok := defaultBindings[id]; ok {
+			if binding.StationUser == "" {
+				binding.StationUser = client.APIClient.ID
+			}
+
+			return binding, "bound in the default profile " + settings.DefaultProfile, true
+		}
+
+		if settings.DefaultProfile == "" {
+			return APIBinding{}, "no default profile is set", false
+		}
+
+		return APIBinding{}, "not bound in the default profile", false
+	}
+
+	return APIBinding{}, "unknown layer", false
+}
+
+// resolve goes through the binding layers in order, the first one with a binding wins. Without one the request is
+// passed through to Epic. It reads the account and party state, so client.mu has to be held.
+func (client *FNRadioClient) resolve(id string) Resolution {
+	var resolution Resolution
+
+	for _, layer := range bindingOrder() {
+		binding, reason, ok := client.resolveLayer(layer, id)
+
+		resolution.Steps = append(resolution.Steps, layer+": "+reason)
+
+		if ok {
+			resolution.Layer = layer
+			resolution.Binding = binding
+
+			return resolution
+		}
+	}
+
+	resolution.Steps = append(resolution.Steps, "passthrough: Fortnite's own audio plays")
+
+	return resolution
+}
+
+// resolveBinding returns the station an in-game station should play right now
+func (client *FNRadioClient) resolveBinding(id string) (APIBinding, bool) {
+	resolution := client.resolve(id)
+
+	return resolution.Binding, resolution.Layer != ""
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const schedulesFile = "schedules.json"
+
+var scheduleMonths = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+var scheduleWeekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Schedule is when a scheduled binding applies, written as a comma separated list of months (dec), weekdays (sat)
+// and a time range (22:00-06:00). Each kind that is given must match, ranges past midnight wrap around and count as
+// the day they started on, so sat,22:00-02:00 still plays in the early hours of Sunday.
+type Schedule struct {
+	Months   []time.Month
+	Weekdays []time.Weekday
+	From     int
+	To       int
+	HasTime  bool
+}
+
+func parseClock(clock string) (int, error) {
+	split := strings.Split(clock, ":")
+	if len(split) != 2 {
+		return 0, errors.New("invalid time " + clock + ", expected HH:MM")
+	}
+
+	hours, err := strconv.Atoi(split[0])
+	if err != nil || hours < 0 || hours > 24 {
+		return 0, errors.New("invalid time " + clock)
+	}
+
+	minutes, err := strconv.Atoi(split[1])
+	if err != nil || minutes < 0 || minutes > 59 || hours == 24 && minutes != 0 {
+		return 0, errors.New("invalid time " + clock)
+	}
+
+	return hours*60 + minutes, nil
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func parseSchedule(when string) (Schedule, error) {
+	var schedule Schedule
+
+	for _, part := range strings.Split(strings.ToLower(when), ",") {
+		part = strings.TrimSpace(part)
+
+		if month := indexOf(scheduleMonths, part); month != -1 {
+			schedule.Months = append(schedule.Months, time.Month(month+1))
+			continue
+		}
+
+		if weekday := indexOf(scheduleWeekdays, part); weekday != -1 {
+			schedule.Weekdays = append(schedule.Weekdays, time.Weekday(weekday))
+			continue
+		}
+
+		clocks := strings.Split(part, "-")
+		if len(clocks) != 2 || schedule.HasTime {
+			return Schedule{}, fmt.Errorf("don't know when %q is", part)
+		}
+
+		var err error
+
+		schedule.From, err = parseClock(clocks[0])
+		if err != nil {
+			return Schedule{}, err
+		}
+
+		schedule.To, err = parseClock(clocks[1])
+		if err != nil {
+			return Schedule{}, err
+		}
+
+		schedule.HasTime = true
+	}
+
+	return schedule, nil
+}
+
+func (schedule Schedule) Matches(t time.Time) bool {
+	day := t
+
+	if schedule.HasTime {
+		minute := t.Hour()*60 + t.Minute()
+
+		switch {
+		case schedule.From <= schedule.To:
+			if minute < schedule.From || minute >= schedule.To {
+				return false
+			}
+		case minute < schedule.To:
+			// Past midnight in a range that started the day before
+			day = t.AddDate(0, 0, -1)
+		case minute < schedule.From:
+			return false
+		}
+	}
+
+	if len(schedule.Months) > 0 {
+		found := false
+
+		for _, month := range schedule.Months {
+			found = found || month == day.Month()
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(schedule.Weekdays) > 0 {
+		found := false
+
+		for _, weekday := range schedule.Weekdays {
+			found = found || weekday == day.Weekday()
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+type ScheduleRule struct {
+	ID          string `json:"id"`
+	StationUser string `json:"station_user"`
+	StationID   string `json:"station_id"`
+	When        string `json:"when"`
+
+	schedule Schedule
+}
+
+func (rule ScheduleRule) Binding() APIBinding {
+	return APIBinding{
+		ID:          rule.ID,
+		StationUser: rule.StationUser,
+		StationID:   rule.StationID,
+	}
+}
+
+// Scheduler keeps the table of scheduled bindings that apply right now, rules earlier in the list win
+type Scheduler struct {
+	mu     sync.Mutex
+	Rules  []ScheduleRule
+	active map[string]APIBinding
+}
+
+var scheduler = &Scheduler{active: map[string]APIBinding{}}
+
+func loadSchedules() error {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	err := loadConfig(schedulesFile, &scheduler.Rules)
+	if err != nil {
+		return err
+	}
+
+	for i, rule := range scheduler.Rules {
+		scheduler.Rules[i].schedule, err = parseSchedule(rule.When)
+		if err != nil {
+			return fmt.Errorf("schedule for %s: %w", getInGameStationName(rule.ID), err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Scheduler) Add(rule ScheduleRule) error {
+	schedule, err := parseSchedule(rule.When)
+	if err != nil {
+		return err
+	}
+
+	rule.schedule = schedule
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Rules = append(s.Rules, rule)
+
+	return saveConfig(schedulesFile, s.Rules)
+}
+
+func (s *Scheduler) Remove(index int) (ScheduleRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if index < 0 || index >= len(s.Rules) {
+		return ScheduleRule{}, errors.New("no schedule with that number")
+	}
+
+	rule := s.Rules[index]
+
+	s.Rules = append(s.Rules[:index:index], s.Rules[index+1:]...)
+
+	return rule, saveConfig(schedulesFile, s.Rules)
+}
+
+func (s *Scheduler) List() []ScheduleRule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]ScheduleRule{}, s.Rules...)
+}
+
+// Update works out which rules apply at t, returning the in-game stations whose scheduled binding changed
+func (s *Scheduler) Update(t time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active := map[string]APIBinding{}
+
+	for _, rule := range s.Rules {
+		if _, ok := active[rule.ID]; !ok && rule.schedule.Matches(t) {
+			active[rule.ID] = rule.Binding()
+		}
+	}
+
+	var changed []string
+
+	for id, binding := range active {
+		if previous, ok := s.active[id]; !ok || previous != binding {
+			changed = append(changed, id)
+		}
+	}
+
+	for id := range s.active {
+		if _, ok := active[id]; !ok {
+			changed = append(changed, id)
+		}
+	}
+
+	s.active = active
+
+	return changed
+}
+
+func (s *Scheduler) Active(id string) (APIBinding, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	binding, ok := s.active[id]
+
+	return binding, ok
+}
+
+func (client *FNRadioClient) runScheduler() {
+	for {
+		now := time.Now()
+
+		for _, id := range scheduler.Update(now) {
+			if binding, ok := scheduler.Active(id); ok {
+				_ = client.Logger.Output(2, "Schedule now plays "+binding.StationID+" on "+getInGameStationName(id))
+			} else {
+				_ = client.Logger.Output(2, "Schedule for "+getInGameStationName(id)+" has ended")
+			}
+		}
+
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+	}
+}
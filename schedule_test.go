@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		clock   string
+		minutes int
+		valid   bool
+	}{
+		{"00:00", 0, true},
+		{"06:30", 390, true},
+		{"23:59", 1439, true},
+		{"24:00", 1440, true},
+		{"24:01", 0, false},
+		{"24:59", 0, false},
+		{"25:00", 0, false},
+		{"12:60", 0, false},
+		{"-1:00", 0, false},
+		{"12", 0, false},
+		{"ab:cd", 0, false},
+	}
+
+	for _, test := range tests {
+		minutes, err := parseClock(test.clock)
+
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("%s: %v", test.clock, err)
+		case !test.valid && err == nil:
+			t.Errorf("%s: expected an error", test.clock)
+		case minutes != test.minutes:
+			t.Errorf("%s: got %d minutes, want %d", test.clock, minutes, test.minutes)
+		}
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	// 2024-03-09 is a Saturday
+	at := func(month time.Month, day int, clock string) time.Time {
+		parsed, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return time.Date(2024, month, day, parsed.Hour(), parsed.Minute(), 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		when  string
+		time  time.Time
+		match bool
+	}{
+		{"22:00-06:00", at(time.March, 9, "23:00"), true},
+		{"22:00-06:00", at(time.March, 9, "05:59"), true},
+		{"22:00-06:00", at(time.March, 9, "06:00"), false},
+		{"22:00-06:00", at(time.March, 9, "21:59"), false},
+		{"08:00-17:00", at(time.March, 9, "08:00"), true},
+		{"08:00-17:00", at(time.March, 9, "17:00"), false},
+		{"20:00-24:00", at(time.March, 9, "23:59"), true},
+		{"sat", at(time.March, 9, "12:00"), true},
+		{"sat", at(time.March, 10, "12:00"), false},
+		{"sat,22:00-02:00", at(time.March, 9, "22:00"), true},
+		{"sat,22:00-02:00", at(time.March, 9, "23:59"), true},
+		{"sat,22:00-02:00", at(time.March, 10, "00:00"), true},
+		{"sat,22:00-02:00", at(time.March, 10, "01:59"), true},
+		{"sat,22:00-02:00", at(time.March, 10, "02:00"), false},
+		{"sat,22:00-02:00", at(time.March, 10, "22:30"), false},
+		{"sat,22:00-02:00", at(time.March, 9, "01:00"), false},
+		{"dec,22:00-02:00", at(time.January, 1, "01:00"), true},
+		{"jan,22:00-02:00", at(time.January, 1, "01:00"), false},
+		{"mar,22:00-02:00", at(time.April, 1, "01:00"), true},
+		{"mar,sat", at(time.March, 9, "12:00"), true},
+		{"mar,sun", at(time.March, 9, "12:00"), false},
+	}
+
+	for _, test := range tests {
+		schedule, err := parseSchedule(test.when)
+		if err != nil {
+			t.Fatalf("%s: %v", test.when, err)
+		}
+
+		if got := schedule.Matches(test.time); got != test.match {
+			t.Errorf("%s at %s: got %t, want %t", test.when, test.time.Format("Mon Jan 2 15:04"), got, test.match)
+		}
+	}
+}
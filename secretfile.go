@@ -0,0 +1,269 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	secretsFile       = "secrets.bin"
+	secretsPassphrase = "FNRADIO_PASSPHRASE"
+	secretsKeySize    = 32
+	secretsSaltSize   = 16
+	secretsIterations = 200000
+
+	// The file starts with how its key is kept, followed by what's needed to get the key back
+	secretsKeyPassphrase byte = 'p'
+	secretsKeyDPAPI      byte = 'd'
+)
+
+// fileCredentials keeps secrets in a file in the config directory encrypted with AES-GCM. The key is derived from
+// FNRADIO_PASSPHRASE or, without one, is random and protected with DPAPI so only the current Windows user can use it.
+type fileCredentials struct {
+	mu sync.Mutex
+}
+
+func dpapi(protect bool, data []byte) ([]byte, error) {
+	in := windows.DataBlob{Size: uint32(len(data))}
+
+	// An empty blob is a nil pointer, there is no first byte to point at
+	if len(data) > 0 {
+		in.Data = &data[0]
+	}
+
+	var out windows.DataBlob
+
+	var err error
+
+	if protect {
+		err = windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	} else {
+		err = windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if out.Data == nil {
+		return []byte{}, nil
+	}
+
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data))) // nolint:errcheck
+
+	return append([]byte{}, unsafe.Slice(out.Data, out.Size)...), nil
+}
+
+func passphraseKey(passphrase string, salt []byte, iterations int) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, iterations, secretsKeySize, sha256.New)
+}
+
+// newSecretsKey makes a key for writing the file, returning it with the mode and key info to store in front of it
+func newSecretsKey() (byte, []byte, []byte, error) {
+	if passphrase := os.Getenv(secretsPassphrase); passphrase != "" {
+		// The iteration count is stored so it can be raised later without losing existing secrets
+		info := make([]byte, secretsSaltSize+4)
+
+		_, err := rand.Read(info[:secretsSaltSize])
+		if err != nil {
+			return 0, nil, nil, err
+		}
+
+		binary.BigEndian.PutUint32(info[secretsSaltSize:], secretsIterations)
+
+		return secretsKeyPassphrase, info, passphraseKey(passphrase, info[:secretsSaltSize], secretsIterations), nil
+	}
+
+	key := make([]byte, secretsKeySize)
+
+	_, err := rand.Read(key)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	info, err := dpapi(true, key)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	return secretsKeyDPAPI, info, key, nil
+}
+
+func openSecretsKey(mode byte, info []byte) ([]byte, error) {
+	switch mode {
+	case secretsKeyPassphrase:
+		passphrase := os.Getenv(secretsPassphrase)
+		if passphrase == "" {
+			return nil, errors.New(secretsFile + " is encrypted with a passphrase, set " + secretsPassphrase)
+		}
+
+		if len(info) != secretsSaltSize+4 {
+			return nil, errors.New(secretsFile + " is damaged")
+		}
+
+		return passphraseKey(passphrase, info[:secretsSaltSize], int(binary.BigEndian.Uint32(info[secretsSaltSize:]))), nil
+	case secretsKeyDPAPI:
+		key, err := dpapi(false, info)
+		if err != nil {
+			return nil, errors.New(secretsFile + " can't be decrypted by this Windows user: " + err.Error())
+		}
+
+		return key, nil
+	}
+
+	return nil, errors.New(secretsFile + " was written by a newer version of FNRadio")
+}
+
+func secretsCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func (f *fileCredentials) read() (map[string][]byte, error) {
+	secrets := map[string][]byte{}
+
+	file, err := configPath(secretsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return secrets, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 5 || len(data)-5 < int(binary.BigEndian.Uint32(data[1:5])) {
+		return nil, errors.New(secretsFile + " is damaged")
+	}
+
+	mode, infoSize := data[0], int(binary.BigEndian.Uint32(data[1:5]))
+
+	key, err := openSecretsKey(mode, data[5:5+infoSize])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := secretsCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[5+infoSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New(secretsFile + " is damaged")
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New(secretsFile + " can't be decrypted, was " + secretsPassphrase + " changed?")
+	}
+
+	return secrets, json.Unmarshal(plaintext, &secrets)
+}
+
+func (f *fileCredentials) write(secrets map[string][]byte) error {
+	file, err := configPath(secretsFile)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	mode, info, key, err := newSecretsKey()
+	if err != nil {
+		return err
+	}
+
+	aead, err := secretsCipher(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+
+	data := []byte{mode, 0, 0, 0, 0}
+
+	binary.BigEndian.PutUint32(data[1:], uint32(len(info)))
+
+	data = append(data, info...)
+	data = append(data, nonce...)
+	data = aead.Seal(data, nonce, plaintext, nil)
+
+	return os.WriteFile(file, data, 0600)
+}
+
+func (f *fileCredentials) Load(name string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := secrets[name]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+
+	return value, nil
+}
+
+func (f *fileCredentials) Save(name string, value []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	secrets[name] = value
+
+	return f.write(secrets)
+}
+
+func (f *fileCredentials) Delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[name]; !ok {
+		return nil
+	}
+
+	delete(secrets, name)
+
+	return f.write(secrets)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+func runSubcommand(name string, args []string) int {
+	switch name {
+	case "apply":
+		return applySubcommand(args)
+	case "replay":
+		return replaySubcommand(args)
+	case "join":
+		return joinSubcommand(args)
+	case "cert":
+		_, err := certCommand(args)
+		if err != nil {
+			fmt.Println(err)
+
+			if errors.Is(err, errCertUsage) {
+				return 2
+			}
+
+			return 1
+		}
+
+		return 0
+	default:
+		fmt.Println("Unknown command " + name)
+		fmt.Println("Usage: fnradio [apply -f <file> | replay <log file> | join <code> | cert <status|rotate|export|uninstall>]")
+
+		return 2
+	}
+}
+
+func applySubcommand(args []string) int {
+	flags := flag.NewFlagSet("apply", flag.ContinueOnError)
+	file := flags.String("f", "", "the profile to apply")
+	dryRun := flags.Bool("dry-run", false, "only print the plan")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *file == "" {
+		fmt.Println("Usage: fnradio apply -f <file> [-dry-run]")
+		return 2
+	}
+
+	api := APIClient{}
+	api.Setup()
+
+	_, err := applyProfile(&api, *file, *dryRun)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	return 0
+}
+
+// joinSubcommand logs in with a code from account link, so a new computer doesn't create an account of its own first
+func joinSubcommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Println("Usage: fnradio join <code>")
+		return 2
+	}
+
+	api, err := JoinPairing(args[0])
+	if err == nil {
+		err = api.Save()
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	fmt.Printf("Logged in as %s\n", api.ID)
+
+	return 0
+}
+
+var errCertUsage = errors.New("usage: cert <status | rotate | export <file> | uninstall>")
+
+// certCommand runs cert status, rotate, export and uninstall, returning the new CA after a rotation
+func certCommand(args []string) (*tls.Certificate, error) {
+	if len(args) == 0 {
+		return nil, errCertUsage
+	}
+
+	if args[0] == "uninstall" {
+		err := uninstallCA()
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Println("The FNRadio certificate has been removed, a new one is made the next time FNRadio starts")
+
+		return nil, nil
+	}
+
+	certificate, err := getCertificate()
+	if err != nil {
+		return nil, errors.New("there is no FNRadio certificate, one is made when FNRadio starts")
+	}
+
+	cert, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case args[0] == "status":
+		trusted, err := trustStore.Contains(cert)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Printf("Fingerprint (SHA-256): %s\n", caFingerprint(cert))
+		fmt.Printf("Expires: %s\n", cert.NotAfter.Local().Format("2006-01-02"))
+		fmt.Printf("Limited to: %s\n", strings.Join(cert.PermittedDNSDomains, ", "))
+		fmt.Printf("Trusted: %t\n", trusted)
+
+		if caNeedsRotation(cert) {
+			fmt.Println("It will be replaced the next time FNRadio starts")
+		}
+	case args[0] == "rotate":
+		certificate, err = rotateCA(cert)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Println("Replaced the FNRadio certificate")
+
+		return certificate, nil
+	case args[0] == "export" && len(args) >= 2:
+		err = exportCA(cert, strings.Join(args[1:], " "))
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Println("Saved the FNRadio certificate to " + strings.Join(args[1:], " "))
+	default:
+		return nil, errCertUsage
+	}
+
+	return nil, nil
+}
+
+// applyProfile converges the current user on the profile in file, removing any station or binding it doesn't list
+func applyProfile(api *APIClient, file string, dryRun bool) (APIUser, error) {
+	profile, err := loadProfile(file)
+	if err != nil {
+		return APIUser{}, err
+	}
+
+	user, err := api.GetUser("@me")
+	if err != nil {
+		return APIUser{}, err
+	}
+
+	actions, err := planProfile(user, api.ID, profile, true)
+	if err != nil {
+		return user, err
+	}
+
+	if len(actions) == 0 {
+		fmt.Println("No changes, stations and bindings match " + file)
+		return user, nil
+	}
+
+	fmt.Printf("Plan: %d change(s)\n", len(actions))
+
+	for _, action := range actions {
+		fmt.Println(action)
+	}
+
+	if dryRun {
+		return user, nil
+	}
+
+	err = executePlan(api, user, actions)
+	if err != nil {
+		return user, err
+	}
+
+	fmt.Println("Apply complete")
+
+	return user, nil
+}
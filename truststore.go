@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	crypt32                              = syscall.NewLazyDLL("crypt32.dll")
+	procCertAddEncodedCertificateToStore = crypt32.NewProc("CertAddEncodedCertificateToStore")
+)
+
+// TrustStore is where the CA has to be for the game to accept the certificates the proxy signs
+type TrustStore interface {
+	Contains(cert *x509.Certificate) (bool, error)
+	Add(cert *x509.Certificate) error
+	Remove(cert *x509.Certificate) error
+}
+
+// windowsRootStore is the current user's trusted root certificate store
+type windowsRootStore struct{}
+
+var trustStore TrustStore = windowsRootStore{}
+
+func (windowsRootStore) open() (windows.Handle, error) {
+	root, err := syscall.UTF16PtrFromString("root")
+	if err != nil {
+		return 0, err
+	}
+
+	return windows.CertOpenStore(10, 0, 0, windows.CERT_SYSTEM_STORE_CURRENT_USER, uintptr(unsafe.Pointer(root)))
+}
+
+// each calls fn with every certificate in the store until it returns false, the context is only valid during the call
+func (s windowsRootStore) each(fn func(store windows.Handle, cert *windows.CertContext) (bool, error)) error {
+	store, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	defer windows.CertCloseStore(store, 0) // nolint:errcheck
+
+	var cert *windows.CertContext
+
+	for {
+		cert, err = windows.CertEnumCertificatesInStore(store, cert)
+		if errors.Is(err, windows.Errno(windows.CRYPT_E_NOT_FOUND)) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		more, err := fn(store, cert)
+		if err != nil || !more {
+			_ = windows.CertFreeCertificateContext(cert)
+
+			return err
+		}
+	}
+}
+
+func (s windowsRootStore) Contains(certToFind *x509.Certificate) (bool, error) {
+	found := false
+
+	err := s.each(func(_ windows.Handle, cert *windows.CertContext) (bool, error) {
+		found = bytes.Equal(unsafe.Slice(cert.EncodedCert, cert.Length), certToFind.Raw)
+
+		return !found, nil
+	})
+
+	return found, err
+}
+
+func (s windowsRootStore) Add(cert *x509.Certificate) error {
+	data := cert.Raw
+
+	store, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	defer windows.CertCloseStore(store, 0) // nolint:errcheck
+
+	_, _, err = procCertAddEncodedCertificateToStore.Call(uintptr(store), 1, uintptr(unsafe.Pointer(&data[0])), uintptr(uint(len(data))), 4, 0)
+	if !errors.Is(err, windows.ERROR_SUCCESS) {
+		return err
+	}
+
+	return nil
+}
+
+func (s windowsRootStore) Remove(certToRemove *x509.Certificate) error {
+	return s.each(func(_ windows.Handle, cert *windows.CertContext) (bool, error) {
+		if !bytes.Equal(unsafe.Slice(cert.EncodedCert, cert.Length), certToRemove.Raw) {
+			return true, nil
+		}
+
+		// Deleting frees the context it's given, so give it a copy and carry on enumerating with ours
+		return true, windows.CertDeleteCertificateFromStore(windows.CertDuplicateCertificateContext(cert))
+	})
+}